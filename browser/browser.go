@@ -1,11 +1,10 @@
 package browser
 
 import (
+	"context"
 	"errors"
 	"log"
-	"net"
 	"net/netip"
-	"sync"
 	"time"
 )
 
@@ -92,6 +91,24 @@ var (
 	// ErrRequestResponseMismatch is returned by functions that request and receive data, but the received data does not match the requested data.
 	ErrRequestResponseMismatch = errors.New("request response mismatch")
 
+	// ErrInvalidResponseHeader is returned if an empty response header or handler is registered
+	ErrInvalidResponseHeader = errors.New("invalid response header")
+
+	// ErrResponseHeaderRegistered is returned if a response header is registered more than once
+	ErrResponseHeaderRegistered = errors.New("response header already registered")
+
+	// ErrAddressFamily is returned if an address does not belong to the address family of a scan
+	ErrAddressFamily = errors.New("address family not used")
+
+	// ErrInvalidQuery is returned by ParseFilter if the query cannot be parsed
+	ErrInvalidQuery = errors.New("invalid filter query")
+
+	// ErrUnknownValue is returned if the text representation of a server flag, skill level, player type or country is unknown
+	ErrUnknownValue = errors.New("unknown value")
+
+	// ErrUnsupportedProtocol is returned if a request is not supported by the selected protocol version
+	ErrUnsupportedProtocol = errors.New("unsupported by protocol version")
+
 	// TokenExpirationDuration sets the protocol expiration time of a token
 	// This variable can be changed
 	TokenExpirationDuration = time.Second * 16
@@ -105,126 +122,146 @@ var (
 		"master4.teeworlds.com:8283",
 	}
 
+	// MasterServerAddresses06 contains the addresses of the master servers that are used with Protocol06 and ProtocolDDNet
+	MasterServerAddresses06 = []string{
+		"master1.teeworlds.com:8300",
+		"master2.teeworlds.com:8300",
+		"master3.teeworlds.com:8300",
+		"master4.teeworlds.com:8300",
+	}
+
 	// ResponsePacketList is a list of known headers that we can expect from either master or game servers
 	ResponsePacketList = [][]byte{
 		[]byte(SendServerCount),
 		[]byte(SendServerList),
 		[]byte(SendInfo),
+		// must be matched after SendInfo, as it is a prefix of SendInfo
+		[]byte(SendInfo06),
+		[]byte(SendInfoExtended),
+		[]byte(SendInfoExtendedMore),
+		[]byte(FirewallCheck),
+		[]byte(FirewallOK),
+		[]byte(FirewallError),
 	}
 )
 
+// GetServerAddresses fetches the server lists of all MasterServerAddresses and returns
+// the merged list of unique game server addresses.
+// Master servers that fail to respond are skipped.
 func GetServerAddresses() ([]netip.AddrPort, error) {
-	clients := make([]*Client, 0, len(MasterServerAddresses))
-	for _, addr := range MasterServerAddresses {
-		client, err := NewClient(addr)
+	return GetServerAddressesContext(context.Background())
+}
+
+// GetServerAddressesContext is like GetServerAddresses but aborts all pending master server
+// requests as soon as the context is done. The addresses that were received up to that point
+// are returned together with the context error.
+// With WithProtocol(Protocol06) or WithProtocol(ProtocolDDNet) the MasterServerAddresses06 are used instead.
+// WithSources replaces the master servers with any other sources.
+func GetServerAddressesContext(ctx context.Context, options ...ScannerOption) ([]netip.AddrPort, error) {
+	cfg := newScanner(options...)
+	list, err := cfg.fetchServerList(ctx)
+
+	result := make([]netip.AddrPort, 0, len(list.Addresses)+len(list.Infos))
+	result = append(result, list.Addresses...)
+	for _, info := range list.Infos {
+		addr, err := netip.ParseAddrPort(info.Address)
 		if err != nil {
-			return nil, err
+			continue
 		}
-		// called at the end of the function, not at the end of the loop
-		defer client.Close()
-		clients = append(clients, client)
-	}
-
-	var (
-		set = make(map[netip.AddrPort]struct{}, 1024)
-		mu  sync.Mutex
-		wg  sync.WaitGroup
-	)
-
-	wg.Add(len(clients))
-	for _, client := range clients {
-		go func(c *Client, s map[netip.AddrPort]struct{}, m *sync.Mutex, w *sync.WaitGroup) {
-			defer wg.Done()
-			// get all addresses from all master servers
-			list, err := c.GetServerAddresses()
-			if err != nil {
-				return
-			}
-			m.Lock()
-			defer m.Unlock()
-			// add all addresses to a global set
-			for _, addr := range list {
-				s[addr] = struct{}{}
-			}
-		}(client, set, &mu, &wg)
-	}
-
-	wg.Wait()
-	result := make([]netip.AddrPort, 0, len(set))
-	for addr := range set {
 		result = append(result, addr)
 	}
-	return result, nil
+	return result, err
 }
 
 // GetServerInfos allows you to fetch a list of server infos directly from Teeworlds servers
 // that you provide here.
 // You can pass domain.name.com:8303 or actual ips in here like ipv4:port or [ipv6]:port
 func GetServerInfosOf(addresses ...string) ([]ServerInfo, error) {
+	return GetServerInfosOfContext(context.Background(), addresses...)
+}
 
-	var (
-		// channel with addresses that need to be fetched
-		udpAddrChan = make(chan *net.UDPAddr, len(addresses))
-		wg          = sync.WaitGroup{}
-	)
-	// push work into channel
-	for _, addr := range addresses {
-		udpAddr, err := resolveUDPAddr(addr)
-		if err != nil {
-			return nil, err
+// GetServerInfosOfContext is like GetServerInfosOf but aborts all pending requests as soon as
+// the context is done. The server infos that were received up to that point are returned
+// together with the context error.
+// In order to configure the scan, e.g. the protocol version, use a Scanner instead.
+func GetServerInfosOfContext(ctx context.Context, addresses ...string) ([]ServerInfo, error) {
+	// a single socket is used for all servers
+	s, err := NewScanner()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.ScanContext(ctx, addresses...)
+}
+
+// StreamServerInfosOf is the streaming variant of GetServerInfosOfContext.
+// The result of every address is sent to the returned channel as soon as it is available.
+// The channel is closed after all servers responded or timed out.
+func StreamServerInfosOf(ctx context.Context, addresses ...string) <-chan ScanResult {
+	results := make(chan ScanResult, len(addresses))
+	go func() {
+		defer close(results)
+		streamServerInfosOf(ctx, addresses, results)
+	}()
+	return results
+}
+
+func streamServerInfosOf(ctx context.Context, addresses []string, results chan<- ScanResult, options ...ScannerOption) {
+	s, err := NewScanner(options...)
+	if err != nil {
+		for _, address := range addresses {
+			results <- ScanResult{Address: address, Err: err}
 		}
-		udpAddrChan <- udpAddr
+		return
 	}
-	// fill the channel and mark as closed afterwards
-	close(udpAddrChan)
-
-	// use global value
-	var (
-		workers = len(addresses)
-		gErr    error
-		mu      = sync.Mutex{}
-		result  = make([]ServerInfo, 0, len(addresses))
-	)
-
-	wg.Add(workers)
-	for i := 0; i < workers; i++ {
-		// start worker routines
-		go func(w *sync.WaitGroup, m *sync.Mutex, id int) {
-			defer w.Done()
-			if Logging {
-				log.Printf("starting worker %d\n", id)
-			}
-			// one client per worker
-			c, err := newClient()
-			if err != nil {
-				gErr = err
-				return
-			}
-			defer c.Close()
-			// iterate over channel until closed
-			for addr := range udpAddrChan {
-				// communicate with target address
-				if Logging {
-					log.Printf("fetching server info for address: %s\n", addr)
-				}
-				c.setTarget(addr)
-				si, err := c.getServerInfo()
-				if err != nil {
-					continue
-				}
-				m.Lock()
-				result = append(result, si)
-				m.Unlock()
+	defer s.Close()
+	s.stream(ctx, addresses, results)
+}
+
+// StreamServerInfos is the streaming variant of GetServerInfosContext.
+// It fetches the server addresses from all sources and sends the result of every
+// game server to the returned channel as soon as it is available.
+// In case the server addresses could not be fetched completely, e.g. because the context is done,
+// the results of the partial server list are sent first, followed by a single result with an
+// empty address and the error. The channel is closed after the scan finished and must be read
+// until then.
+func StreamServerInfos(ctx context.Context, options ...ScannerOption) <-chan ScanResult {
+	results := make(chan ScanResult, 1)
+	go func() {
+		defer close(results)
+
+		cfg := newScanner(options...)
+		list, err := cfg.fetchServerList(ctx)
+
+		// server infos that were provided by the sources do not need to be scanned
+		for _, info := range list.Infos {
+			results <- ScanResult{Address: info.Address, Info: info}
+		}
+
+		addresses := make([]string, 0, len(list.Addresses))
+		for _, addr := range list.Addresses {
+			addresses = append(addresses, addr.String())
+		}
+
+		if len(addresses) > 0 {
+			// every address produces at most one result
+			scanResults := make(chan ScanResult, len(addresses))
+			go func() {
+				defer close(scanResults)
+				streamServerInfosOf(ctx, addresses, scanResults, options...)
+			}()
+
+			// forward the results to the already returned channel
+			for result := range scanResults {
+				results <- result
 			}
-		}(&wg, &mu, i)
-	}
+		}
 
-	// wait for workers to finish work
-	wg.Wait()
-	if gErr != nil {
-		return nil, gErr
-	}
-	return result, nil
+		if err != nil {
+			results <- ScanResult{Err: err}
+		}
+	}()
+	return results
 }
 
 func missing(all []netip.AddrPort, found map[string]ServerInfo) []string {
@@ -245,19 +282,51 @@ func missing(all []netip.AddrPort, found map[string]ServerInfo) []string {
 	return result
 }
 
+// GetServerInfos fetches the server addresses from all master servers and
+// returns the server infos of all game servers that responded.
+// Use GetServerInfosContext with WithSources in order to combine the UDP master servers
+// with HTTP master servers.
 func GetServerInfos() ([]ServerInfo, error) {
-	list, err := GetServerAddresses()
+	return GetServerInfosContext(context.Background())
+}
+
+// GetServerInfosContext is like GetServerInfos but aborts the scan as soon as the context is done.
+// The server infos that were received up to that point are returned together with the context error.
+// The options configure the master server requests as well as the scan of the game servers.
+func GetServerInfosContext(ctx context.Context, options ...ScannerOption) ([]ServerInfo, error) {
+	cfg := newScanner(options...)
+	servers, err := cfg.fetchServerList(ctx)
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	// after a context error, only the server infos of the partial server list are returned
+	return scanServerList(ctx, servers, options...)
+}
+
+// scanServerList scans all addresses of the server list and returns them together
+// with the server infos that were already part of the list.
+func scanServerList(ctx context.Context, servers ServerList, options ...ScannerOption) ([]ServerInfo, error) {
+	list := servers.Addresses
+	checkList := make(map[string]ServerInfo, len(list)+len(servers.Infos))
+	// server infos that were provided by the sources do not need to be scanned
+	for _, info := range servers.Infos {
+		checkList[info.Address] = info
+	}
+
+	// a single socket is used for all servers
+	s, err := NewScanner(options...)
 	if err != nil {
 		return nil, err
 	}
-	checkList := make(map[string]ServerInfo, len(list))
+	defer s.Close()
 
 	retries := 0
-	for mis := missing(list, checkList); len(mis) > 0 && retries < 2; mis = missing(list, checkList) {
+	for mis := missing(list, checkList); len(mis) > 0 && retries < 2 && ctx.Err() == nil; mis = missing(list, checkList) {
 		retries++
 
-		infos, err := GetServerInfosOf(mis...)
-		if err != nil {
+		// partial results are kept in case of a context error
+		infos, err := s.ScanContext(ctx, mis...)
+		if err != nil && ctx.Err() == nil {
 			continue
 		}
 
@@ -271,6 +340,8 @@ func GetServerInfos() ([]ServerInfo, error) {
 		result = append(result, info)
 
 	}
-	return result, nil
-
+	if s.group {
+		result = GroupServerInfos(result)
+	}
+	return result, ctx.Err()
 }
@@ -1,41 +1,138 @@
 package browser_test
 
 import (
+	"context"
+	"net/netip"
 	"testing"
 	"time"
 
 	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
 	"github.com/jxsl13/twapi/internal/testutils/require"
 )
 
+// useMasters replaces the global master server list for the duration of the test.
+// Tests that use this function must not run in parallel.
+func useMasters(t *testing.T, masters ...*browsertest.Master) {
+	t.Helper()
+	old := browser.MasterServerAddresses
+	browser.MasterServerAddresses = make([]string, 0, len(masters))
+	for _, m := range masters {
+		browser.MasterServerAddresses = append(browser.MasterServerAddresses, m.Addr)
+	}
+	t.Cleanup(func() {
+		browser.MasterServerAddresses = old
+	})
+}
+
 func TestGetServerAddresses(t *testing.T) {
-	t.Parallel()
+	servers := serverAddresses(150)
+	// overlapping server lists must be merged
+	useMasters(t,
+		newMaster(t, servers[:100]...),
+		newMaster(t, servers[50:]...),
+	)
 
 	start := time.Now()
 	u, err := browser.GetServerAddresses()
 	diff := time.Since(start)
 	require.NoError(t, err)
 	require.NotZero(t, len(u), "found %d server addresses in %d milliseconds", len(u), diff.Milliseconds())
+	require.Len(t, len(servers), u)
+}
+
+// addrPort parses the address of a fake server
+func addrPort(t *testing.T, address string) netip.AddrPort {
+	t.Helper()
+	addr, err := netip.ParseAddrPort(address)
+	require.NoError(t, err)
+	return addr
 }
 
 func TestGetServerInfos(t *testing.T) {
-	t.Parallel()
+	servers := make([]netip.AddrPort, 0, 3)
+	for _, name := range []string{"first", "second", "third"} {
+		servers = append(servers, addrPort(t, newServer(t, serverInfo(name)).Addr))
+	}
+	useMasters(t, newMaster(t, servers...))
 
 	start := time.Now()
 	u, err := browser.GetServerInfos()
 	diff := time.Since(start)
 	require.NoError(t, err)
+	require.Len(t, len(servers), u)
 	t.Logf("found %d server infos in %d milliseconds", len(u), diff.Milliseconds())
 }
 
 func TestServerInfoOfSingleServer(t *testing.T) {
 	t.Parallel()
+	expected := serverInfo("single server")
+	s := newServer(t, expected)
+	expected.Address = s.Addr
 
 	start := time.Now()
-	u, err := browser.GetServerInfosOf(SimplyzCatch)
+	u, err := browser.GetServerInfosOf(s.Addr)
 	diff := time.Since(start)
 	require.NoError(t, err)
 	require.Len(t, 1, u)
+	require.True(t, u[0].Equal(expected), "expected: %s, got: %s", expected.String(), u[0].String())
 
 	t.Logf("found %d server infos in %d milliseconds", len(u), diff.Milliseconds())
 }
+
+func TestServerInfosOfBrokenServers(t *testing.T) {
+	t.Parallel()
+	healthy := newServer(t, serverInfo("healthy"))
+	truncated := newServer(t, serverInfo("truncated"))
+	truncated.SetFaults(browsertest.Faults{Truncate: 10})
+
+	corrupted := newServer(t, serverInfo("corrupted"))
+	corrupted.SetFaults(browsertest.Faults{Corrupt: 1})
+
+	u, err := browser.GetServerInfosOf(healthy.Addr, truncated.Addr, corrupted.Addr)
+	require.NoError(t, err)
+	require.Len(t, 2, u)
+	names := map[string]string{u[0].Address: u[0].Name, u[1].Address: u[1].Name}
+	require.Equal(t, "healthy", names[healthy.Addr])
+	// a single broken response is retried like a lost packet
+	require.Equal(t, "corrupted", names[corrupted.Addr])
+	require.Equal(t, 2, corrupted.Requests())
+	// the default scanner gives up after two retries
+	require.Equal(t, 3, truncated.Requests())
+}
+
+func TestServerInfosOfContextDeadline(t *testing.T) {
+	t.Parallel()
+	healthy := newServer(t, serverInfo("healthy"))
+	unreachable := newServer(t, serverInfo("unreachable"))
+	unreachable.SetFaults(browsertest.Faults{Loss: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	u, err := browser.GetServerInfosOfContext(ctx, healthy.Addr, unreachable.Addr)
+	diff := time.Since(start)
+	require.ErrorIs(t, context.DeadlineExceeded, err)
+	require.Less(t, 2*time.Second, diff)
+	require.Len(t, 1, u)
+	require.Equal(t, healthy.Addr, u[0].Address)
+}
+
+func TestGetServerInfosContextCanceled(t *testing.T) {
+	healthy := newServer(t, serverInfo("healthy"))
+	delayed := newServer(t, serverInfo("delayed"))
+	delayed.SetFaults(browsertest.Faults{Delay: time.Minute})
+	useMasters(t, newMaster(t, addrPort(t, healthy.Addr), addrPort(t, delayed.Addr)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+
+	start := time.Now()
+	u, err := browser.GetServerInfosContext(ctx)
+	diff := time.Since(start)
+	require.ErrorIs(t, context.Canceled, err)
+	require.Less(t, 2*time.Second, diff)
+	require.Len(t, 1, u)
+	require.Equal(t, healthy.Addr, u[0].Address)
+}
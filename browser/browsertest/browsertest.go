@@ -0,0 +1,256 @@
+// Package browsertest provides fake master servers and game servers that speak
+// the Teeworlds 0.7, 0.6 and DDNet connless protocols on a local UDP port.
+// They allow to test the browser package without any internet connection.
+package browsertest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+)
+
+const (
+	// Packet flags of the upper six bits of the first header byte
+	netPacketFlagControl  = 1
+	netPacketFlagConnless = 8
+	netPacketVersion      = 1
+
+	netControlMessageToken  = 5
+	netTokenRequestDataSize = 512
+	netTokenNone            = 0xffffffff
+
+	tokenRequestSize  = 7 + netTokenRequestDataSize
+	connlessHeaderLen = 9
+
+	// 0.6 connless packets do not contain any tokens
+	connlessHeader06 = "\xff\xff\xff\xff\xff\xff"
+	// DDNet extended server info requests replace the 0.6 header with
+	// this prefix that is followed by four bytes of extra data
+	connlessHeaderExtended    = "xe"
+	connlessHeaderExtendedLen = len(connlessHeaderExtended) + 4
+
+	maxBufferSize = 1500
+)
+
+// loopback is the default address of all fake servers
+var loopback = netip.AddrPortFrom(netip.AddrFrom4([4]byte{127, 0, 0, 1}), 0)
+
+// request is a connless packet that was received from a client that
+// either already owns a valid token or uses the 0.6 protocol.
+type request struct {
+	Addr *net.UDPAddr
+	// Legacy is true for 0.6 requests that do not have any tokens
+	Legacy bool
+	// Extended is true for DDNet requests of the extended server info.
+	// Extended requests are always Legacy requests.
+	Extended bool
+	// Token is the token that the server issued to the client
+	Token uint32
+	// ResponseToken is the client's own token that must be sent back with every response
+	ResponseToken uint32
+	Payload       []byte
+}
+
+// listener is the UDP loop that is shared by all fake servers.
+// It answers token requests and forwards connless requests with
+// a valid token to the handler.
+type listener struct {
+	conn    *net.UDPConn
+	handler func(request)
+
+	// write sends a packet to the client, it may be replaced in order to inject failures.
+	write func(addr *net.UDPAddr, data []byte) error
+	// token returns the token that is sent to the client after a token request.
+	token func(addr *net.UDPAddr) uint32
+
+	mu     sync.Mutex
+	tokens map[string]uint32
+
+	wg sync.WaitGroup
+}
+
+// listen binds a new listener to the local address, port 0 picks a random port.
+// The listener does not process any packets before start is called.
+func listen(addr netip.AddrPort, handler func(request)) (*listener, error) {
+	conn, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(addr))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &listener{
+		conn:    conn,
+		handler: handler,
+		tokens:  make(map[string]uint32),
+	}
+	l.write = l.writeToUDP
+	l.token = l.issueToken
+	return l, nil
+}
+
+func (l *listener) start() {
+	l.wg.Add(1)
+	go l.serve()
+}
+
+// Addr returns the ip:port address the listener is bound to
+func (l *listener) Addr() string {
+	return l.conn.LocalAddr().String()
+}
+
+// Close stops the listener and waits for the serving goroutine to return
+func (l *listener) Close() error {
+	err := l.conn.Close()
+	l.wg.Wait()
+	return err
+}
+
+func (l *listener) serve() {
+	defer l.wg.Done()
+
+	buffer := [maxBufferSize]byte{}
+	for {
+		n, addr, err := l.conn.ReadFromUDP(buffer[:])
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		// the handler may keep the payload, so it gets its own copy
+		data := make([]byte, n)
+		copy(data, buffer[:n])
+		l.dispatch(addr, data)
+	}
+}
+
+func (l *listener) dispatch(addr *net.UDPAddr, data []byte) {
+	switch {
+	case isConnless06(data):
+		l.handler(request{
+			Addr:    addr,
+			Legacy:  true,
+			Payload: data[len(connlessHeader06):],
+		})
+	case isConnlessExtended(data):
+		l.handler(request{
+			Addr:     addr,
+			Legacy:   true,
+			Extended: true,
+			Payload:  data[connlessHeaderExtendedLen:],
+		})
+	case isTokenRequest(data):
+		clientToken := binary.BigEndian.Uint32(data[8:12])
+		_ = l.write(addr, tokenResponse(clientToken, l.token(addr)))
+	case isConnless(data):
+		token := binary.BigEndian.Uint32(data[1:5])
+		if !l.validToken(addr, token) {
+			// the real servers silently drop packets with unknown tokens
+			return
+		}
+		l.handler(request{
+			Addr:          addr,
+			Token:         token,
+			ResponseToken: binary.BigEndian.Uint32(data[5:9]),
+			Payload:       data[connlessHeaderLen:],
+		})
+	}
+}
+
+func (l *listener) issueToken(addr *net.UDPAddr) uint32 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := addr.String()
+	token, ok := l.tokens[key]
+	if ok {
+		return token
+	}
+	token = newToken()
+	l.tokens[key] = token
+	return token
+}
+
+func (l *listener) validToken(addr *net.UDPAddr, token uint32) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	issued, ok := l.tokens[addr.String()]
+	return ok && issued == token
+}
+
+// reply sends a connless response with the given header and payload back to the requesting client
+func (l *listener) reply(req request, header string, payload []byte) error {
+	return l.write(req.Addr, responsePacket(req, header, payload))
+}
+
+func (l *listener) writeToUDP(addr *net.UDPAddr, data []byte) error {
+	_, err := l.conn.WriteToUDP(data, addr)
+	return err
+}
+
+func responsePacket(req request, header string, payload []byte) []byte {
+	data := make([]byte, 0, connlessHeaderLen+len(header)+len(payload))
+	if req.Legacy {
+		data = append(data, connlessHeader06...)
+	} else {
+		data = append(data, connlessHeader(req.ResponseToken, req.Token)...)
+	}
+	data = append(data, header...)
+	data = append(data, payload...)
+	return data
+}
+
+func isTokenRequest(data []byte) bool {
+	return len(data) >= tokenRequestSize &&
+		data[0]>>2 == netPacketFlagControl &&
+		data[7] == netControlMessageToken
+}
+
+func isConnless06(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(connlessHeader06))
+}
+
+func isConnlessExtended(data []byte) bool {
+	return len(data) >= connlessHeaderExtendedLen &&
+		bytes.HasPrefix(data, []byte(connlessHeaderExtended))
+}
+
+func isConnless(data []byte) bool {
+	return len(data) >= connlessHeaderLen &&
+		(data[0]>>2)&netPacketFlagConnless != 0
+}
+
+// tokenResponse is the control message that the server sends after receiving a token request.
+func tokenResponse(clientToken, serverToken uint32) []byte {
+	data := [12]byte{}
+	data[0] = (netPacketFlagControl << 2) & 0b11111100
+	binary.BigEndian.PutUint32(data[3:7], clientToken)
+	data[7] = netControlMessageToken
+	binary.BigEndian.PutUint32(data[8:12], serverToken)
+	return data[:]
+}
+
+// connlessHeader is the prefix of every connless packet.
+// token is the token of the receiver and responseToken the token of the sender.
+func connlessHeader(token, responseToken uint32) []byte {
+	header := [connlessHeaderLen]byte{}
+	header[0] = ((netPacketFlagConnless << 2) & 0b11111100) | (netPacketVersion & 0b00000011)
+	binary.BigEndian.PutUint32(header[1:5], token)
+	binary.BigEndian.PutUint32(header[5:9], responseToken)
+	return header[:]
+}
+
+func newToken() uint32 {
+	buffer := [4]byte{}
+	for {
+		_, _ = rand.Read(buffer[:])
+		token := binary.BigEndian.Uint32(buffer[:])
+		if token != netTokenNone {
+			return token
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package browsertest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/jxsl13/twapi/browser"
+)
+
+const (
+	serversPerChunk = 75
+	serverEntrySize = 18 // 16 bytes IPv4/IPv6 and 2 bytes port
+)
+
+// NewMaster starts a fake master server on a random local UDP port.
+// The master server answers server count and server list requests
+// with the passed server addresses. Game servers that register with
+// heartbeats are added to the list after a successful firewall check.
+func NewMaster(servers ...netip.AddrPort) (*Master, error) {
+	m := &Master{}
+	m.SetServers(servers...)
+
+	l, err := listen(loopback, m.handle)
+	if err != nil {
+		return nil, err
+	}
+	m.l = l
+	m.Addr = l.Addr()
+	l.start()
+	return m, nil
+}
+
+// Master is a fake master server that can be used with browser.NewClient
+// or by adding its address to browser.MasterServerAddresses.
+type Master struct {
+	// Addr is the ip:port address that the master server listens on
+	Addr string
+
+	l *listener
+
+	mu      sync.Mutex
+	servers []netip.AddrPort
+	faults  MasterFaults
+	// pending firewall checks by the announced game server address
+	pending map[string]request
+}
+
+// SetServers replaces the server addresses that are registered at the master server
+func (m *Master) SetServers(servers ...netip.AddrPort) {
+	list := make([]netip.AddrPort, 0, len(servers))
+	for _, addr := range servers {
+		list = append(list, netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port()))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.servers = list
+}
+
+// MasterFaults configures the misbehavior of a fake master server
+type MasterFaults struct {
+	// DropChunks contains the indices of the server list packets that are not sent
+	DropChunks []int
+}
+
+// SetFaults replaces the faults of the master server
+func (m *Master) SetFaults(f MasterFaults) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = f
+}
+
+// Servers returns the server addresses that are registered at the master server
+func (m *Master) Servers() []netip.AddrPort {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]netip.AddrPort(nil), m.servers...)
+}
+
+// Close shuts down the master server
+func (m *Master) Close() error {
+	return m.l.Close()
+}
+
+func (m *Master) handle(req request) {
+	if bytes.HasPrefix(req.Payload, []byte(browser.Heartbeat)) {
+		m.handleHeartbeat(req)
+		return
+	}
+	switch string(req.Payload) {
+	case browser.RequestServerCount:
+		count := len(m.Servers())
+		_ = m.l.reply(req, browser.SendServerCount, []byte{byte(count >> 8), byte(count)})
+	case browser.RequestServerList:
+		servers := m.Servers()
+		m.mu.Lock()
+		drop := m.faults.DropChunks
+		m.mu.Unlock()
+		for start := 0; start < len(servers); start += serversPerChunk {
+			if slices.Contains(drop, start/serversPerChunk) {
+				continue
+			}
+			end := min(start+serversPerChunk, len(servers))
+			_ = m.l.reply(req, browser.SendServerList, marshalServerList(servers[start:end]))
+		}
+	case browser.FirewallResponse:
+		m.handleFirewallResponse(req)
+	}
+}
+
+// handleHeartbeat starts the firewall check of the announced game server address
+func (m *Master) handleHeartbeat(req request) {
+	data := req.Payload[len(browser.Heartbeat):]
+	if len(data) != 2 {
+		return
+	}
+	port := binary.BigEndian.Uint16(data)
+	check := req
+	check.Addr = &net.UDPAddr{IP: req.Addr.IP, Port: int(port), Zone: req.Addr.Zone}
+
+	m.mu.Lock()
+	if m.pending == nil {
+		m.pending = make(map[string]request, 1)
+	}
+	m.pending[check.Addr.String()] = req
+	m.mu.Unlock()
+
+	_ = m.l.reply(check, browser.FirewallCheck, nil)
+}
+
+// handleFirewallResponse lists the game server and confirms the registration
+func (m *Master) handleFirewallResponse(req request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	heartbeat, ok := m.pending[req.Addr.String()]
+	if !ok {
+		return
+	}
+	delete(m.pending, req.Addr.String())
+
+	addr := req.Addr.AddrPort()
+	addr = netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+	if !slices.Contains(m.servers, addr) {
+		m.servers = append(m.servers, addr)
+	}
+	_ = m.l.reply(heartbeat, browser.FirewallOK, nil)
+}
+
+// marshalServerList creates the payload of a single server list chunk
+func marshalServerList(servers []netip.AddrPort) []byte {
+	data := make([]byte, 0, len(servers)*serverEntrySize)
+	for _, addr := range servers {
+		// IPv4 addresses are sent as IPv4-mapped IPv6 addresses
+		ip := addr.Addr().As16()
+		data = append(data, ip[:]...)
+		data = append(data, byte(addr.Port()>>8), byte(addr.Port()))
+	}
+	return data
+}
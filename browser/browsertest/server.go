@@ -0,0 +1,291 @@
+package browsertest
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/compression"
+)
+
+// header of the server info request without the trailing request token
+var requestInfoHeader = []byte(strings.TrimSuffix(browser.RequestInfo, "\x00"))
+
+// header of the server info response without the trailing request token
+var sendInfoHeader = strings.TrimSuffix(browser.SendInfo, "\x00")
+
+// Faults allows to inject network and protocol failures into a fake game server.
+// The zero value describes a perfectly working server.
+type Faults struct {
+	// Loss is the probability in the range [0, 1] that an outgoing packet is dropped
+	Loss float64
+	// Drop is the number of outgoing packets that are dropped before the server starts to respond
+	Drop int
+	// Delay is the duration that every outgoing packet is delayed
+	Delay time.Duration
+	// Truncate cuts the server info payload down to the given number of bytes if it is positive
+	Truncate int
+	// DropParts contains the indices of the DDNet extended server info parts that are never sent
+	DropParts []int
+	// Corrupt is the number of 0.7 and 0.6 server info responses that are cut in half
+	// before the server starts to respond with complete server infos
+	Corrupt int
+	// WrongToken makes the server respond to token requests with a token that
+	// it does not accept afterwards, as if it had rotated its token seed.
+	WrongToken bool
+}
+
+// NewServer starts a fake game server on a random local UDP port
+// that answers 0.7, 0.6 and DDNet extended server info requests with the passed server info.
+func NewServer(info browser.ServerInfo) (*Server, error) {
+	return NewServerOn(loopback, info)
+}
+
+// NewServerOn is like NewServer but listens on the passed local address, e.g. on the IPv6 loopback
+// address [::1]:8303. Port 0 picks a random port.
+func NewServerOn(addr netip.AddrPort, info browser.ServerInfo) (*Server, error) {
+	s := &Server{
+		info: info,
+	}
+
+	l, err := listen(addr, s.handle)
+	if err != nil {
+		return nil, err
+	}
+	l.write = s.write
+	l.token = s.token
+
+	s.l = l
+	s.Addr = l.Addr()
+	l.start()
+	return s, nil
+}
+
+// Server is a fake game server that can be used with browser.NewClient,
+// browser.GetServerInfosOf or be registered at a fake Master.
+type Server struct {
+	// Addr is the ip:port address that the game server listens on
+	Addr string
+
+	l *listener
+
+	mu        sync.Mutex
+	info      browser.ServerInfo
+	faults    Faults
+	requests  int
+	dropped   int
+	corrupted int
+	custom    map[string]CustomHandler
+}
+
+// CustomHandler creates the response to a custom connless request.
+// The payload is the data that follows the request header.
+type CustomHandler func(payload []byte) (responseHeader string, response []byte)
+
+// Handle registers a handler for requests that start with a custom header, e.g. of a mod.
+// The handler's response is sent back with the same framing as the request.
+func (s *Server) Handle(requestHeader string, handler CustomHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.custom == nil {
+		s.custom = make(map[string]CustomHandler, 1)
+	}
+	s.custom[requestHeader] = handler
+}
+
+// SetInfo replaces the server info that is sent to clients
+func (s *Server) SetInfo(info browser.ServerInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info = info
+}
+
+// SetFaults replaces the currently injected failures
+func (s *Server) SetFaults(f Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = f
+	s.dropped = 0
+	s.corrupted = 0
+}
+
+// Requests returns the number of server info requests with a valid token that the server received
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Close shuts down the game server
+func (s *Server) Close() error {
+	return s.l.Close()
+}
+
+func (s *Server) getFaults() Faults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.faults
+}
+
+func (s *Server) handle(req request) {
+	if s.handleCustom(req) {
+		return
+	}
+	if !bytes.HasPrefix(req.Payload, requestInfoHeader) {
+		return
+	}
+	// the client sends a token that must be sent back with the server info
+	tokenData := req.Payload[len(requestInfoHeader):]
+	if len(tokenData) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	s.requests++
+	info := s.info
+	truncate := s.faults.Truncate
+	dropParts := s.faults.DropParts
+	corrupt := s.corrupted < s.faults.Corrupt
+	if corrupt {
+		s.corrupted++
+	}
+	s.mu.Unlock()
+
+	if req.Extended {
+		s.replyExtended(req, info, tokenData[0], truncate, dropParts)
+		return
+	}
+
+	var (
+		payload []byte
+		err     error
+	)
+	if req.Legacy {
+		payload, err = marshalInfo06(info, tokenData[0])
+	} else {
+		payload, err = marshalInfo(info, tokenData)
+	}
+	if err != nil {
+		return
+	}
+
+	if truncate > 0 && truncate < len(payload) {
+		payload = payload[:truncate]
+	}
+	if corrupt {
+		payload = payload[:len(payload)/2]
+	}
+	_ = s.l.reply(req, sendInfoHeader, payload)
+}
+
+// handleCustom answers requests with a header that was registered with Handle
+func (s *Server) handleCustom(req request) bool {
+	s.mu.Lock()
+	var (
+		handler CustomHandler
+		header  string
+	)
+	for h, hdl := range s.custom {
+		if strings.HasPrefix(string(req.Payload), h) {
+			handler, header = hdl, h
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if handler == nil {
+		return false
+	}
+	responseHeader, response := handler(req.Payload[len(header):])
+	_ = s.l.reply(req, responseHeader, response)
+	return true
+}
+
+// replyExtended sends the DDNet extended server info that is split into
+// multiple parts if it does not fit into a single packet.
+func (s *Server) replyExtended(req request, info browser.ServerInfo, token byte, truncate int, dropParts []int) {
+	parts, err := info.MarshalBinaryExtended()
+	if err != nil {
+		return
+	}
+	for idx, data := range parts {
+		if slices.Contains(dropParts, idx) {
+			continue
+		}
+		p := compression.NewPacker(make([]byte, 0, len(data)+4))
+		p.AddString(strconv.Itoa(int(token)))
+		p.AddBytes(data)
+		payload := p.Bytes()
+
+		if truncate > 0 && truncate < len(payload) {
+			payload = payload[:truncate]
+		}
+		header := browser.SendInfoExtended
+		if idx > 0 {
+			header = browser.SendInfoExtendedMore
+		}
+		_ = s.l.reply(req, header, payload)
+	}
+}
+
+// marshalInfo creates the 0.7 server info payload that starts with the varint request token
+func marshalInfo(info browser.ServerInfo, tokenData []byte) ([]byte, error) {
+	browseToken, n := compression.Varint(tokenData)
+	if n <= 0 {
+		return nil, compression.ErrNoDataToUnpack
+	}
+	data, err := info.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	payload := compression.AppendVarint(make([]byte, 0, len(data)+5), browseToken)
+	return append(payload, data...), nil
+}
+
+// marshalInfo06 creates the 0.6 server info payload that starts with the request token as string
+func marshalInfo06(info browser.ServerInfo, token byte) ([]byte, error) {
+	data, err := info.MarshalBinary06()
+	if err != nil {
+		return nil, err
+	}
+	p := compression.NewPacker(make([]byte, 0, len(data)+4))
+	p.AddString(strconv.Itoa(int(token)))
+	p.AddBytes(data)
+	return p.Bytes(), nil
+}
+
+func (s *Server) token(addr *net.UDPAddr) uint32 {
+	if s.getFaults().WrongToken {
+		// never stored, thus never accepted
+		return newToken()
+	}
+	return s.l.issueToken(addr)
+}
+
+func (s *Server) write(addr *net.UDPAddr, data []byte) error {
+	s.mu.Lock()
+	f := s.faults
+	drop := s.dropped < f.Drop
+	if drop {
+		s.dropped++
+	}
+	s.mu.Unlock()
+
+	if drop || (f.Loss > 0 && rand.Float64() < f.Loss) {
+		return nil
+	}
+	if f.Delay > 0 {
+		time.AfterFunc(f.Delay, func() {
+			_ = s.l.writeToUDP(addr, data)
+		})
+		return nil
+	}
+	return s.l.writeToUDP(addr, data)
+}
@@ -0,0 +1,310 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cache persists the results of a CachedBrowser, e.g. on disk or in a database.
+type Cache interface {
+	// Load returns the last stored snapshot.
+	// An empty snapshot and no error are returned if nothing was stored yet.
+	Load(ctx context.Context) (CacheSnapshot, error)
+	// Store replaces the stored snapshot
+	Store(ctx context.Context, snapshot CacheSnapshot) error
+}
+
+// CacheSnapshot is the state of a CachedBrowser that is persisted in a Cache
+type CacheSnapshot struct {
+	// ServerList contains the addresses that were received from the master servers
+	ServerList CachedServerList `json:"server_list"`
+	// Servers contains the last known server infos
+	Servers []CachedServerInfo `json:"servers"`
+}
+
+// CachedServerList is the merged server list of all master servers
+type CachedServerList struct {
+	Addresses []netip.AddrPort `json:"addresses"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// CachedServerInfo is a server info together with the time it was received
+type CachedServerInfo struct {
+	ServerInfo
+	UpdatedAt time.Time `json:"updated_at"`
+	// Stale is set if the server info is older than the maximum age of the CachedBrowser
+	Stale bool `json:"stale,omitempty"`
+}
+
+// NewFileCache creates a cache that stores the snapshot as JSON file at the given path
+func NewFileCache(path string) *FileCache {
+	return &FileCache{
+		path: path,
+	}
+}
+
+// FileCache stores the snapshot as JSON file
+type FileCache struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Load reads the snapshot from the file. A missing file results in an empty snapshot.
+func (fc *FileCache) Load(ctx context.Context) (CacheSnapshot, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	var snapshot CacheSnapshot
+	data, err := os.ReadFile(fc.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return CacheSnapshot{}, fmt.Errorf("invalid cache file %s: %w", fc.path, err)
+	}
+	return snapshot, nil
+}
+
+// Store replaces the file atomically, readers never see a partially written file
+func (fc *FileCache) Store(ctx context.Context, snapshot CacheSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	f, err := os.CreateTemp(filepath.Dir(fc.path), filepath.Base(fc.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fc.path)
+}
+
+// NewMemoryCache creates a cache that only lives as long as the process
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{}
+}
+
+// MemoryCache keeps the snapshot in memory
+type MemoryCache struct {
+	mu       sync.Mutex
+	snapshot CacheSnapshot
+}
+
+// Load returns the last stored snapshot
+func (mc *MemoryCache) Load(ctx context.Context) (CacheSnapshot, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.snapshot, nil
+}
+
+// Store replaces the snapshot
+func (mc *MemoryCache) Store(ctx context.Context, snapshot CacheSnapshot) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.snapshot = snapshot
+	return nil
+}
+
+// NewCachedBrowser creates a browser that serves the server infos of the cache and refreshes them
+// in the background as soon as they are older than maxAge.
+// The options configure the master server requests as well as the scan of the game servers.
+func NewCachedBrowser(cache Cache, maxAge time.Duration, options ...ScannerOption) *CachedBrowser {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CachedBrowser{
+		cache:   cache,
+		maxAge:  maxAge,
+		options: options,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// CachedBrowser fetches the server infos of all game servers like GetServerInfos,
+// but keeps the results in a Cache in order to answer immediately, even after a restart.
+type CachedBrowser struct {
+	cache   Cache
+	maxAge  time.Duration
+	options []ScannerOption
+
+	// background refreshes
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	loaded     bool
+	snapshot   CacheSnapshot
+	refreshing bool
+	// refreshMu serializes the refreshes
+	refreshMu sync.Mutex
+}
+
+// Close stops a running background refresh
+func (b *CachedBrowser) Close() error {
+	b.cancel()
+	b.wg.Wait()
+	return nil
+}
+
+// ServerInfos returns the cached server infos right away. Server infos that are older than
+// the maximum age are marked as stale and a refresh is started in the background.
+// Only if the cache is empty, the server infos are fetched before returning.
+func (b *CachedBrowser) ServerInfos(ctx context.Context) ([]CachedServerInfo, error) {
+	err := b.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	empty := len(b.snapshot.Servers) == 0
+	b.mu.Unlock()
+	if empty {
+		err = b.Refresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		now    = time.Now()
+		stale  = false
+		result = make([]CachedServerInfo, 0, len(b.snapshot.Servers))
+	)
+	for _, info := range b.snapshot.Servers {
+		info.Stale = now.Sub(info.UpdatedAt) > b.maxAge
+		stale = stale || info.Stale
+		result = append(result, info)
+	}
+	if stale {
+		b.refreshInBackground()
+	}
+	return result, nil
+}
+
+// refreshInBackground starts a refresh unless one is already running.
+// Must be called with the mutex held.
+func (b *CachedBrowser) refreshInBackground() {
+	if b.refreshing || b.ctx.Err() != nil {
+		return
+	}
+	b.refreshing = true
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		err := b.Refresh(b.ctx)
+		if err != nil && Logging {
+			log.Printf("failed to refresh cached server infos: %v\n", err)
+		}
+		b.mu.Lock()
+		b.refreshing = false
+		b.mu.Unlock()
+	}()
+}
+
+// load reads the snapshot from the cache once
+func (b *CachedBrowser) load(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.loaded {
+		return nil
+	}
+	snapshot, err := b.cache.Load(ctx)
+	if err != nil {
+		return err
+	}
+	b.snapshot = snapshot
+	b.loaded = true
+	return nil
+}
+
+// Refresh fetches the server lists and the server infos and stores them in the cache.
+// In case no master server responds, the cached server list is scanned instead.
+func (b *CachedBrowser) Refresh(ctx context.Context) error {
+	b.refreshMu.Lock()
+	defer b.refreshMu.Unlock()
+
+	err := b.load(ctx)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	snapshot := b.snapshot
+	b.mu.Unlock()
+
+	cfg := newScanner(b.options...)
+	servers, err := cfg.fetchServerList(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == nil && len(servers.Addresses)+len(servers.Infos) > 0 {
+		addresses := make([]netip.AddrPort, 0, len(servers.Addresses)+len(servers.Infos))
+		addresses = append(addresses, servers.Addresses...)
+		for _, info := range servers.Infos {
+			addr, err := netip.ParseAddrPort(info.Address)
+			if err == nil {
+				addresses = append(addresses, addr)
+			}
+		}
+		snapshot.ServerList = CachedServerList{
+			Addresses: addresses,
+			UpdatedAt: time.Now(),
+		}
+	} else if len(snapshot.ServerList.Addresses) > 0 {
+		// fall back to the last known server list
+		servers = ServerList{Addresses: snapshot.ServerList.Addresses}
+	} else if err != nil {
+		return err
+	}
+
+	infos, err := scanServerList(ctx, servers, b.options...)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 && len(snapshot.Servers) > 0 {
+		// most likely a network issue, keep the last known server infos
+		return ErrTimeout
+	}
+
+	now := time.Now()
+	snapshot.Servers = make([]CachedServerInfo, 0, len(infos))
+	for _, info := range infos {
+		snapshot.Servers = append(snapshot.Servers, CachedServerInfo{
+			ServerInfo: info,
+			UpdatedAt:  now,
+		})
+	}
+
+	b.mu.Lock()
+	b.snapshot = snapshot
+	b.mu.Unlock()
+	return b.cache.Store(ctx, snapshot)
+}
@@ -0,0 +1,108 @@
+package browser_test
+
+import (
+	"context"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func TestFileCache(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cache := browser.NewFileCache(filepath.Join(t.TempDir(), "cache.json"))
+
+	snapshot, err := cache.Load(ctx)
+	require.NoError(t, err)
+	require.Len(t, 0, snapshot.Servers)
+
+	info := serverInfo("cached")
+	info.Address = "127.0.0.1:8303"
+	expected := browser.CacheSnapshot{
+		ServerList: browser.CachedServerList{
+			Addresses: []netip.AddrPort{netip.MustParseAddrPort(info.Address)},
+			UpdatedAt: time.Now().Truncate(time.Second),
+		},
+		Servers: []browser.CachedServerInfo{{ServerInfo: info, UpdatedAt: time.Now().Truncate(time.Second)}},
+	}
+	require.NoError(t, cache.Store(ctx, expected))
+
+	snapshot, err = cache.Load(ctx)
+	require.NoError(t, err)
+	require.Equal(t, expected.ServerList.Addresses, snapshot.ServerList.Addresses)
+	require.True(t, expected.ServerList.UpdatedAt.Equal(snapshot.ServerList.UpdatedAt))
+	require.Len(t, 1, snapshot.Servers)
+	require.True(t, snapshot.Servers[0].Equal(info))
+}
+
+func TestCachedBrowserWarmStart(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	s := newServer(t, serverInfo("cached"))
+	m := newMaster(t, addrPort(t, s.Addr))
+	options := []browser.ScannerOption{
+		browser.WithSources(browser.NewMasterSource(browser.Protocol07, m.Addr)),
+		browser.WithScanTimeout(500 * time.Millisecond),
+		browser.WithRetryInterval(100 * time.Millisecond),
+	}
+
+	cold := browser.NewCachedBrowser(browser.NewFileCache(path), time.Hour, options...)
+	infos, err := cold.ServerInfos(ctx)
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+	require.False(t, infos[0].Stale)
+	require.NoError(t, cold.Close())
+
+	// after a restart the cached infos are served without any network access
+	require.NoError(t, m.Close())
+	require.NoError(t, s.Close())
+
+	warm := browser.NewCachedBrowser(browser.NewFileCache(path), 0, options...)
+	start := time.Now()
+	infos, err = warm.ServerInfos(ctx)
+	require.Less(t, 100*time.Millisecond, time.Since(start))
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+	require.True(t, infos[0].Stale)
+	require.Equal(t, "cached", infos[0].Name)
+	require.Equal(t, s.Addr, infos[0].Address)
+
+	// the failed background refresh keeps the cached infos
+	require.NoError(t, warm.Close())
+	infos, err = warm.ServerInfos(ctx)
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+}
+
+func TestCachedBrowserRefresh(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := newServer(t, serverInfo("before"))
+	m := newMaster(t, addrPort(t, s.Addr))
+
+	cache := browser.NewMemoryCache()
+	b := browser.NewCachedBrowser(cache, time.Hour,
+		browser.WithSources(browser.NewMasterSource(browser.Protocol07, m.Addr)),
+	)
+	defer b.Close()
+
+	infos, err := b.ServerInfos(ctx)
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+	require.Equal(t, "before", infos[0].Name)
+
+	s.SetInfo(serverInfo("after"))
+	require.NoError(t, b.Refresh(ctx))
+
+	snapshot, err := cache.Load(ctx)
+	require.NoError(t, err)
+	require.Len(t, 1, snapshot.Servers)
+	require.Equal(t, "after", snapshot.Servers[0].Name)
+	require.Len(t, 1, snapshot.ServerList.Addresses)
+}
@@ -1,19 +1,27 @@
 package browser
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
 	"net"
 	"net/netip"
+	"os"
 	"sync"
 	"time"
 )
 
-func newClient() (*Client, error) {
+// newClient creates a client with a socket of the address family that is bound to the local address.
+// A nil local address binds the socket to a random port of all local addresses.
+func newClient(family AddressFamily, laddr *net.UDPAddr) (*Client, error) {
 	c := &Client{
 		tokenCache: newTokenCache(),
+		family:     family,
 	}
 
 	var err error
-	c.conn, err = net.ListenUDP("udp", nil)
+	c.conn, err = net.ListenUDP(family.network(), laddr)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +48,11 @@ func newClient() (*Client, error) {
 // Currently it is also possible to pass a game server address to this constructor in order to
 // fetch that specific server's server info.
 func NewClient(address string) (*Client, error) {
-	c, err := newClient()
+	return newClientTo(address, AddressFamilyAny, nil)
+}
+
+func newClientTo(address string, family AddressFamily, laddr *net.UDPAddr) (*Client, error) {
+	c, err := newClient(family, laddr)
 	if err != nil {
 		return nil, err
 	}
@@ -59,12 +71,14 @@ type Client struct {
 	readTimeout  time.Duration
 	writeTimeout time.Duration
 	tokenCache   *tokenCache
+	protocol     ProtocolVersion
+	family       AddressFamily
 
 	mu sync.Mutex
 }
 
 func (c *Client) SetTarget(address string) error {
-	udpAddr, err := resolveUDPAddr(address)
+	udpAddr, err := c.family.resolveUDPAddr(address)
 	if err != nil {
 		return err
 	}
@@ -78,6 +92,14 @@ func (c *Client) setTarget(address *net.UDPAddr) {
 	c.target = address
 }
 
+// SetProtocol sets the protocol version that is used to communicate with the target.
+// The default is Protocol07.
+func (c *Client) SetProtocol(p ProtocolVersion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.protocol = p
+}
+
 func (c *Client) SetReadTimeout(d time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -107,65 +129,122 @@ func (c *Client) Close() error {
 }
 
 // tries to write all of the data provided
-func (c *Client) write(data []byte) (int, error) {
-	return c.writeToUDP(data, c.target)
+func (c *Client) write(ctx context.Context, data []byte) (int, error) {
+	return c.writeToUDP(ctx, data, c.target)
 }
 
 // tries to write all of the data provided
-func (c *Client) writeToUDP(data []byte, addr *net.UDPAddr) (int, error) {
+func (c *Client) writeToUDP(ctx context.Context, data []byte, addr *net.UDPAddr) (int, error) {
 	var (
 		expected = len(data)
 		written  = 0
 	)
 
 	for written < expected {
-		err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		d := deadline(ctx, c.writeTimeout)
+		err := c.conn.SetWriteDeadline(d)
 		if err != nil {
 			return -1, err
 		}
 		i, err := c.conn.WriteToUDP(data, addr)
 		if err != nil {
-			return written, err
+			return written, contextError(ctx, d, err)
 		}
 		written += i
 	}
 	return written, nil
 }
 
-func (c *Client) read(data []byte) (int, error) {
-	err := c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+// read blocks until a packet is received, the read timeout is exceeded or the context is done.
+// In case the context is done, the context error is returned.
+func (c *Client) read(ctx context.Context, data []byte) (int, error) {
+	d := deadline(ctx, c.readTimeout)
+	err := c.conn.SetReadDeadline(d)
 	if err != nil {
 		return 0, err
 	}
-	return c.conn.Read(data)
+
+	// unblock the pending read as soon as the context is canceled
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	n, err := c.conn.Read(data)
+	return n, contextError(ctx, d, err)
+}
+
+// contextError returns the context error in case the socket operation was aborted by the context.
+// The socket deadline d may be the context's deadline and fire shortly before the context is done,
+// in that case contextError waits for the context, so that the caller observes the same error.
+func contextError(ctx context.Context, d time.Time, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxDeadline, ok := ctx.Deadline(); ok && !ctxDeadline.After(d) && errors.Is(err, os.ErrDeadlineExceeded) {
+		<-ctx.Done()
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
 }
 
-/*
-// TODO: unused for now
-func (c *Client) readFromUDP(data []byte) (int, *net.UDPAddr, error) {
-	err := c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+// deadline returns the earlier point in time of either now + timeout or the context's deadline
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	d := time.Now().Add(timeout)
+	ctxDeadline, ok := ctx.Deadline()
+	if ok && ctxDeadline.Before(d) {
+		return ctxDeadline
+	}
+	return d
+}
+
+// readFromUDP is like read but accepts packets from any address
+func (c *Client) readFromUDP(ctx context.Context, data []byte) (int, *net.UDPAddr, error) {
+	d := deadline(ctx, c.readTimeout)
+	err := c.conn.SetReadDeadline(d)
 	if err != nil {
 		return 0, nil, err
 	}
-	return c.conn.ReadFromUDP(data)
+
+	// unblock the pending read as soon as the context is canceled
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	n, addr, err := c.conn.ReadFromUDP(data)
+	return n, addr, contextError(ctx, d, err)
 }
-*/
 
 // unguarded variant
-func (c *Client) getToken() (*Token, error) {
+func (c *Client) getToken(ctx context.Context) (*Token, error) {
+	if c.protocol.legacy() {
+		return nil, ErrUnsupportedProtocol
+	}
 	addr := c.target.String()
 	// no need to refresh token if it has not yet expired
 	if !c.tokenCache.Get(addr).Expired() {
 		return c.tokenCache.Get(addr), nil
 	}
+	return c.requestToken(ctx)
+}
+
+// requestToken requests a new token and replaces the cached one
+func (c *Client) requestToken(ctx context.Context) (*Token, error) {
+	addr := c.target.String()
 	// TODO: check if we need to process the number of written bytes
-	_, err := c.write(NewTokenRequestPacket())
+	_, err := c.write(ctx, NewTokenRequestPacket())
 	if err != nil {
 		return nil, err
 	}
 	buffer := [maxBufferSize]byte{}
 	response := buffer[:]
-	i, err := c.read(response)
+	i, err := c.read(ctx, response)
 	if err != nil {
 		return nil, err
 	}
@@ -183,15 +262,23 @@ func (c *Client) getToken() (*Token, error) {
 
 // GetToken returns a client/server token that secures the connection against IP spoofing
 func (c *Client) GetToken() (*Token, error) {
+	return c.GetTokenContext(context.Background())
+}
+
+// GetTokenContext is like GetToken but aborts as soon as the context is done.
+func (c *Client) GetTokenContext(ctx context.Context) (*Token, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.getToken()
+	return c.getToken(ctx)
 }
 
 // request creates the request payload
-func (c *Client) request(header string) ([]byte, error) {
+func (c *Client) request(ctx context.Context, header string) ([]byte, error) {
+	if c.protocol.legacy() {
+		return NewRequestPacket06(header), nil
+	}
 	// refresh token if not expired
-	token, err := c.getToken()
+	token, err := c.getToken(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -208,25 +295,87 @@ func (c *Client) request(header string) ([]byte, error) {
 }
 
 // send request and receive a single chunk with the response
-func (c *Client) get(header string) (*ResponsePacket, error) {
-	request, err := c.request(header)
+func (c *Client) get(ctx context.Context, header string) (*ResponsePacket, error) {
+	request, err := c.request(ctx, header)
 	if err != nil {
 		return nil, err
 	}
-	_, err = c.write(request)
+	_, err = c.write(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 	buffer := [maxBufferSize]byte{}
-	response := buffer[:]
-	i, err := c.read(response)
+	return c.readResponse(ctx, buffer[:])
+}
+
+// readResponse reads responses into the buffer until a response is received that
+// is not handled by a custom response handler.
+func (c *Client) readResponse(ctx context.Context, buffer []byte) (*ResponsePacket, error) {
+	for {
+		i, err := c.read(ctx, buffer)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.unmarshalResponse(buffer[:i])
+		if err != nil {
+			return nil, err
+		}
+		handled, err := dispatch(resp)
+		if !handled {
+			return resp, nil
+		}
+		if err != nil && Logging {
+			log.Printf("failed to handle response %q from %s: %v\n", resp.ResponseHeader, c.target, err)
+		}
+	}
+}
+
+// Request sends a request with a custom header to the target and returns the first response.
+// In case the response header was registered with RegisterResponseHeader, the response is
+// passed to its handler and the handler's error is returned.
+func (c *Client) Request(header string) (*ResponsePacket, error) {
+	return c.RequestContext(context.Background(), header)
+}
+
+// RequestContext is like Request but aborts as soon as the context is done.
+func (c *Client) RequestContext(ctx context.Context, header string) (*ResponsePacket, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	request, err := c.request(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.write(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := [maxBufferSize]byte{}
+	i, err := c.read(ctx, buffer[:])
 	if err != nil {
 		return nil, err
 	}
-	data := response[:i]
+	// the response outlives the buffer
+	resp, err := c.unmarshalResponse(bytes.Clone(buffer[:i]))
+	if err != nil {
+		return nil, err
+	}
+	_, err = dispatch(resp)
+	return resp, err
+}
 
-	resp := &ResponsePacket{}
-	err = resp.UnmarshalBinary(data)
+// unmarshalResponse parses the response depending on the protocol version
+func (c *Client) unmarshalResponse(data []byte) (*ResponsePacket, error) {
+	resp := &ResponsePacket{
+		Addr: c.target,
+	}
+	var err error
+	if c.protocol.legacy() {
+		err = resp.UnmarshalBinary06(data)
+	} else {
+		err = resp.UnmarshalBinary(data)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -235,13 +384,18 @@ func (c *Client) get(header string) (*ResponsePacket, error) {
 
 // GetServerCount returns the number of registered servers for the current master server
 func (c *Client) GetServerCount() (int, error) {
+	return c.GetServerCountContext(context.Background())
+}
+
+// GetServerCountContext is like GetServerCount but aborts as soon as the context is done.
+func (c *Client) GetServerCountContext(ctx context.Context) (int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.getServerCount()
+	return c.getServerCount(ctx)
 }
 
-func (c *Client) getServerCount() (int, error) {
-	resp, err := c.get(RequestServerCount)
+func (c *Client) getServerCount(ctx context.Context) (int, error) {
+	resp, err := c.get(ctx, RequestServerCount)
 	if err != nil {
 		return -1, err
 	}
@@ -254,10 +408,16 @@ func (c *Client) getServerCount() (int, error) {
 
 // GetServerAddresses returns a list of server addresses from the underlying master server
 func (c *Client) GetServerAddresses() ([]netip.AddrPort, error) {
+	return c.GetServerAddressesContext(context.Background())
+}
+
+// GetServerAddressesContext is like GetServerAddresses but aborts as soon as the context is done.
+// The addresses that were received up to that point are returned together with the context error.
+func (c *Client) GetServerAddressesContext(ctx context.Context) ([]netip.AddrPort, error) {
 	c.mu.Lock()
-	list, err := c.getServerAddresses()
+	list, err := c.getServerAddresses(ctx, nil)
 	c.mu.Unlock()
-	if err != nil {
+	if err != nil && len(list) == 0 {
 		return nil, err
 	}
 
@@ -270,11 +430,13 @@ func (c *Client) GetServerAddresses() ([]netip.AddrPort, error) {
 	for addr := range set {
 		list = append(list, addr)
 	}
-	return list, nil
+	return list, err
 }
 
-func (c *Client) getServerAddresses() ([]netip.AddrPort, error) {
-	expectedServers, err := c.getServerCount()
+// getServerAddresses returns the addresses that were received so far in case of an error.
+// The counts of the health report are updated unless it is nil.
+func (c *Client) getServerAddresses(ctx context.Context, h *MasterHealth) ([]netip.AddrPort, error) {
+	expectedServers, err := c.getServerCount(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -282,14 +444,19 @@ func (c *Client) getServerAddresses() ([]netip.AddrPort, error) {
 	if expectedServers%maxServersPerChunk > 0 {
 		expectedChunks += 1
 	}
+	if h != nil {
+		h.Reachable = true
+		h.Advertised = expectedServers
+		h.ChunksExpected = expectedChunks
+	}
 
 	result := make([]netip.AddrPort, 0, expectedServers)
 
-	request, err := c.request(RequestServerList)
+	request, err := c.request(ctx, RequestServerList)
 	if err != nil {
 		return nil, err
 	}
-	_, err = c.write(request)
+	_, err = c.write(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -297,47 +464,103 @@ func (c *Client) getServerAddresses() ([]netip.AddrPort, error) {
 	// that we wanna parse
 	buffer := [maxBufferSize]byte{}
 	for i := 0; i < expectedChunks; i++ {
-		response := buffer[:]
-		i, err := c.read(response)
+		resp, err := c.readResponse(ctx, buffer[:])
 		if err != nil {
-			return nil, err
-		}
-		data := response[:i]
-
-		resp := ResponsePacket{}
-		err = resp.UnmarshalBinary(data)
-		if err != nil {
-			return nil, err
+			return result, err
 		}
 
 		list, err := parseServerList(resp.Payload)
 		if err != nil {
-			return nil, err
+			return result, err
 		}
 		result = append(result, list...)
+		if h != nil {
+			h.ChunksReceived++
+			h.Received = len(result)
+		}
 	}
 
 	return result, nil
 }
 
-func (c *Client) getServerInfo() (si ServerInfo, err error) {
-	resp, err := c.get(RequestInfo)
+func (c *Client) getServerInfo(ctx context.Context) (si ServerInfo, err error) {
+	if c.protocol == ProtocolDDNet {
+		return c.getServerInfoExtended(ctx)
+	}
+	// the token request is not part of the measured round trip time
+	request, err := c.request(ctx, RequestInfo)
+	if err != nil {
+		return si, err
+	}
+	start := time.Now()
+	_, err = c.write(ctx, request)
+	if err != nil {
+		return si, err
+	}
+	buffer := [maxBufferSize]byte{}
+	resp, err := c.readResponse(ctx, buffer[:])
+	if err != nil {
+		return si, err
+	}
+	rtt := time.Since(start)
+
+	var info ServerInfo
+	if c.protocol.legacy() {
+		info, err = parseServerInfo06(resp.Payload, c.target.String())
+	} else {
+		info, err = parseServerInfo(resp.Payload, c.target.String())
+	}
 	if err != nil {
 		return si, err
 	}
 
-	info, err := parseServerInfo(resp.Payload, c.target.String())
+	info.Latency = newLatency([]time.Duration{rtt})
+	return info, nil
+}
+
+// getServerInfoExtended requests the DDNet extended server info and
+// reads response parts until all players were received.
+func (c *Client) getServerInfoExtended(ctx context.Context) (si ServerInfo, err error) {
+	start := time.Now()
+	_, err = c.write(ctx, NewRequestPacketDDNet(RequestInfo))
 	if err != nil {
 		return si, err
 	}
 
+	var (
+		buffer = [maxBufferSize]byte{}
+		merger = MultiPartServerInfo{}
+		rtt    time.Duration
+	)
+	for !merger.Complete() {
+		resp, err := c.readResponse(ctx, buffer[:])
+		if err != nil {
+			return si, err
+		}
+		if rtt == 0 {
+			rtt = time.Since(start)
+		}
+		err = merger.Add(resp)
+		if err != nil {
+			return si, err
+		}
+	}
+
+	info := merger.ServerInfo()
+	info.Address = c.target.String()
+	info.Latency = newLatency([]time.Duration{rtt})
 	return info, nil
 }
 
 // GetServerInfo returns the server info of a game server.
 // This function requires the target to be set to a game server address
 func (c *Client) GetServerInfo() (ServerInfo, error) {
+	return c.GetServerInfoContext(context.Background())
+}
+
+// GetServerInfoContext is like GetServerInfo but aborts as soon as the context is done.
+func (c *Client) GetServerInfoContext(ctx context.Context) (ServerInfo, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.getServerInfo()
+	return c.getServerInfo(ctx)
 }
@@ -1,27 +1,90 @@
 package browser_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/netip"
 	"testing"
 	"time"
 
 	"github.com/jxsl13/twapi/browser"
-)
-
-var (
-	SimplyzCatch = "89.163.148.121:8303"
-	MasterServer = "master1.teeworlds.com:8283"
+	"github.com/jxsl13/twapi/browser/browsertest"
 )
 
 func init() {
 	browser.Logging = true
 }
 
+// newMaster starts a fake master server that is shut down at the end of the test
+func newMaster(t *testing.T, servers ...netip.AddrPort) *browsertest.Master {
+	t.Helper()
+	m, err := browsertest.NewMaster(servers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = m.Close()
+	})
+	return m
+}
+
+// newServer starts a fake game server that is shut down at the end of the test
+func newServer(t *testing.T, info browser.ServerInfo) *browsertest.Server {
+	t.Helper()
+	s, err := browsertest.NewServer(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+	return s
+}
+
+// serverInfo creates a server info with two players.
+// The address is set to the empty string and must be set to the fake server's address
+// in order to compare it with a received server info.
+func serverInfo(name string) browser.ServerInfo {
+	players := browser.PlayerInfos{
+		{Name: "nameless tee", Clan: "clan", Type: 0, Country: 276, Score: 12},
+		{Name: "brainless tee", Clan: "", Type: 1, Country: -1, Score: -3},
+	}
+	return browser.ServerInfo{
+		Version:     "0.7.5",
+		Name:        name,
+		Hostname:    "",
+		Map:         "ctf5",
+		GameType:    "CTF",
+		ServerFlags: 1,
+		SkillLevel:  2,
+		NumPlayers:  1,
+		MaxPlayers:  8,
+		NumClients:  len(players),
+		MaxClients:  16,
+		Players:     players,
+	}
+}
+
+// serverAddresses generates n unique IPv4 and IPv6 server addresses
+func serverAddresses(n int) []netip.AddrPort {
+	list := make([]netip.AddrPort, 0, n)
+	for i := 0; i < n; i++ {
+		port := uint16(8303 + i%16)
+		if i%2 == 0 {
+			list = append(list, netip.AddrPortFrom(netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)}), port))
+		} else {
+			list = append(list, netip.AddrPortFrom(netip.AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 14: byte(i >> 8), 15: byte(i)}), port))
+		}
+	}
+	return list
+}
+
 func TestClientGetToken(t *testing.T) {
 	t.Parallel()
+	m := newMaster(t)
 
-	c, err := browser.NewClient(MasterServer)
+	c, err := browser.NewClient(m.Addr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -58,8 +121,10 @@ func TestClientGetToken(t *testing.T) {
 
 func TestClientGetServerCount(t *testing.T) {
 	t.Parallel()
+	// more than 255 servers require both bytes of the server count
+	m := newMaster(t, serverAddresses(300)...)
 
-	c, err := browser.NewClient(MasterServer)
+	c, err := browser.NewClient(m.Addr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -70,18 +135,21 @@ func TestClientGetServerCount(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if i <= 0 {
-		t.Fatal("<= 0 server count from master servers")
+	if i != 300 {
+		t.Fatalf("expected 300 registered servers, got %d", i)
 	} else {
-		t.Logf("%s has %d registered servers", MasterServer, i)
+		t.Logf("%s has %d registered servers", m.Addr, i)
 	}
 
 }
 
 func TestClientGetServerAddresses(t *testing.T) {
 	t.Parallel()
+	// multiple chunks of server lists
+	servers := serverAddresses(200)
+	m := newMaster(t, servers...)
 
-	c, err := browser.NewClient(MasterServer)
+	c, err := browser.NewClient(m.Addr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -104,12 +172,24 @@ func TestClientGetServerAddresses(t *testing.T) {
 	if len(set) != len(list) {
 		t.Fatalf("expected unique servers %d, unique servers %d", len(list), len(set))
 	}
+
+	if len(list) != len(servers) {
+		t.Fatalf("expected %d servers, got %d", len(servers), len(list))
+	}
+	for _, addr := range servers {
+		if _, ok := set[addr]; !ok {
+			t.Errorf("missing server: %s", addr)
+		}
+	}
 }
 
 func TestClientGetServerInfo(t *testing.T) {
 	t.Parallel()
+	expected := serverInfo("fake server")
+	s := newServer(t, expected)
+	expected.Address = s.Addr
 
-	c, err := browser.NewClient(SimplyzCatch)
+	c, err := browser.NewClient(s.Addr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -120,6 +200,10 @@ func TestClientGetServerInfo(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	if !si.Equal(expected) {
+		t.Fatalf("expected: %s, got: %s", expected.String(), si.String())
+	}
+
 	b, err := json.MarshalIndent(si, "", " ")
 	if err != nil {
 		t.Fatal(err)
@@ -129,8 +213,9 @@ func TestClientGetServerInfo(t *testing.T) {
 
 func TestGetSingleMasterServer(t *testing.T) {
 	t.Parallel()
+	m := newMaster(t, serverAddresses(10)...)
 
-	client, err := browser.NewClient(MasterServer)
+	client, err := browser.NewClient(m.Addr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -143,3 +228,98 @@ func TestGetSingleMasterServer(t *testing.T) {
 
 	t.Log(addresses)
 }
+
+func TestClientGetServerInfoFaults(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		faults  browsertest.Faults
+		wantErr bool
+	}{
+		{"no faults", browsertest.Faults{}, false},
+		{"short delay", browsertest.Faults{Delay: 20 * time.Millisecond}, false},
+		{"long delay", browsertest.Faults{Delay: time.Second}, true},
+		{"packet loss", browsertest.Faults{Loss: 1}, true},
+		{"truncated payload", browsertest.Faults{Truncate: 20}, true},
+		{"wrong token", browsertest.Faults{WrongToken: true}, true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s := newServer(t, serverInfo(tc.name))
+			s.SetFaults(tc.faults)
+
+			c, err := browser.NewClient(s.Addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			c.SetReadTimeout(200 * time.Millisecond)
+
+			si, err := c.GetServerInfo()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got server info: %s", si.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if si.Name != tc.name {
+				t.Fatalf("expected server name %q, got %q", tc.name, si.Name)
+			}
+		})
+	}
+}
+
+func TestClientGetServerInfoContextCanceled(t *testing.T) {
+	t.Parallel()
+	s := newServer(t, serverInfo("delayed"))
+	s.SetFaults(browsertest.Faults{Delay: time.Minute})
+
+	c, err := browser.NewClient(s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = c.GetServerInfoContext(ctx)
+	diff := time.Since(start)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	if diff > time.Second {
+		t.Fatalf("pending read was not aborted, took %s", diff)
+	}
+}
+
+func TestClientGetServerInfoContextDeadline(t *testing.T) {
+	t.Parallel()
+	s := newServer(t, serverInfo("delayed"))
+	s.SetFaults(browsertest.Faults{Delay: time.Minute})
+
+	c, err := browser.NewClient(s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetReadTimeout(time.Minute)
+
+	// the socket deadline equals the context deadline and may fire first
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err = c.GetServerInfoContext(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+		}
+	}
+}
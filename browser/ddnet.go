@@ -0,0 +1,332 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jxsl13/twapi/compression"
+)
+
+const (
+	// SendInfoExtended is used by DDNet game servers when sending the first part of the extended server info.
+	// It is followed by the request token as string.
+	SendInfoExtended = "\xff\xff\xff\xffiext"
+	// SendInfoExtendedMore is used by DDNet game servers when sending any further part of the extended server info.
+	// It is followed by the request token as string.
+	SendInfoExtendedMore = "\xff\xff\xff\xffiex+"
+
+	// connlessHeaderExtended is the prefix of DDNet connless packets that request the extended server info.
+	// It is followed by four bytes of extra data, the first two of them extend the request token.
+	connlessHeaderExtended = "xe"
+
+	// DDNet servers start a new part if a part exceeds this size
+	maxExtendedPartSize = 1400 - 18
+)
+
+// NewRequestPacketDDNet creates a new request payload that asks DDNet servers for the extended server info.
+func NewRequestPacketDDNet(requestHeader string) []byte {
+	payload := make([]byte, 0, len(connlessHeaderExtended)+4+len(requestHeader))
+	payload = append(payload, connlessHeaderExtended...)
+	payload = append(payload, 0, 0, 0, 0) // extra token and reserved bytes
+	payload = append(payload, requestHeader...)
+	return payload
+}
+
+// MultiPartServerInfo merges the parts of a DDNet extended server info into a single ServerInfo.
+// The first part (SendInfoExtended) contains the server's information and the first players,
+// all further parts (SendInfoExtendedMore) contain the remaining players.
+type MultiPartServerInfo struct {
+	info      ServerInfo
+	hasHeader bool
+	parts     map[int]PlayerInfos
+}
+
+// Add parses a response packet of either the type SendInfoExtended or SendInfoExtendedMore.
+// Parts may be added in any order, duplicate parts are ignored.
+func (m *MultiPartServerInfo) Add(resp *ResponsePacket) error {
+	if m.parts == nil {
+		m.parts = make(map[int]PlayerInfos, 1)
+	}
+
+	u := compression.NewUnpacker(resp.Payload)
+	// skip request token
+	_, err := u.NextString()
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal request token: %w", err)
+	}
+
+	switch resp.ResponseHeader {
+	case SendInfoExtended:
+		if m.hasHeader {
+			return nil
+		}
+		info := ServerInfo{}
+		players, err := info.unmarshalExtended(u)
+		if err != nil {
+			return err
+		}
+		m.info = info
+		m.hasHeader = true
+		m.parts[0] = players
+	case SendInfoExtendedMore:
+		part, err := nextIntString(u)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal part number: %w", err)
+		}
+		if part <= 0 {
+			return fmt.Errorf("%w: invalid part number %d", ErrMalformedResponseData, part)
+		}
+		// extra info, reserved
+		_, err = u.NextString()
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal extra info: %w", err)
+		}
+		if _, ok := m.parts[part]; ok {
+			return nil
+		}
+		players, err := unmarshalPlayersExtended(u)
+		if err != nil {
+			return err
+		}
+		m.parts[part] = players
+	default:
+		return fmt.Errorf("%w: %q", ErrUnexpectedResponseHeader, resp.ResponseHeader)
+	}
+	return nil
+}
+
+// Complete returns true as soon as the first part and all players were received
+func (m *MultiPartServerInfo) Complete() bool {
+	if !m.hasHeader {
+		return false
+	}
+	players := 0
+	for _, part := range m.parts {
+		players += len(part)
+	}
+	return players >= m.info.NumClients
+}
+
+// hasFirstPart returns true if the part with the server's information was received
+func (m *MultiPartServerInfo) hasFirstPart() bool {
+	return m != nil && m.hasHeader
+}
+
+// ServerInfo returns the merged server info with the players of all parts that were received so far
+func (m *MultiPartServerInfo) ServerInfo() ServerInfo {
+	info := m.info
+
+	keys := make([]int, 0, len(m.parts))
+	for part := range m.parts {
+		keys = append(keys, part)
+	}
+	sort.Ints(keys)
+
+	info.Players = make(PlayerInfos, 0, info.NumClients)
+	for _, part := range keys {
+		info.Players = append(info.Players, m.parts[part]...)
+	}
+	return info
+}
+
+// MarshalBinaryExtended returns the parts of the DDNet extended server info without the request tokens.
+// The first part is sent with the SendInfoExtended header, all further parts with the SendInfoExtendedMore header.
+func (s *ServerInfo) MarshalBinaryExtended() ([][]byte, error) {
+	p := compression.NewPacker()
+	p.AddString(s.Version)
+	p.AddString(s.Name)
+	p.AddString(s.Map)
+	p.AddString("0") // map crc
+	p.AddString("0") // map size
+	p.AddString(s.GameType)
+	p.AddString(fmt.Sprint(int(s.ServerFlags)))
+	p.AddString(fmt.Sprint(s.NumPlayers))
+	p.AddString(fmt.Sprint(s.MaxPlayers))
+	p.AddString(fmt.Sprint(len(s.Players)))
+	p.AddString(fmt.Sprint(s.MaxClients))
+	p.AddString("") // extra info, reserved
+
+	parts := make([][]byte, 0, 1)
+	for _, player := range s.Players {
+		data, err := player.marshalBinaryExtended()
+		if err != nil {
+			return nil, err
+		}
+		if p.Size()+len(data) >= maxExtendedPartSize {
+			parts = append(parts, p.Bytes())
+			p = compression.NewPacker()
+			p.AddString(fmt.Sprint(len(parts)))
+			p.AddString("") // extra info, reserved
+		}
+		p.AddBytes(data)
+	}
+	return append(parts, p.Bytes()), nil
+}
+
+// unmarshalExtended parses the first part of the extended server info and returns the contained players
+func (s *ServerInfo) unmarshalExtended(u *compression.Unpacker) (PlayerInfos, error) {
+	var err error
+	s.Version, err = u.NextString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal version: %w", err)
+	}
+
+	s.Name, err = u.NextString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal name: %w", err)
+	}
+
+	s.Map, err = u.NextString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal map: %w", err)
+	}
+
+	_, err = nextIntString(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal map crc: %w", err)
+	}
+	_, err = nextIntString(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal map size: %w", err)
+	}
+
+	s.GameType, err = u.NextString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal gametype: %w", err)
+	}
+
+	flags, err := nextIntString(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal server flags: %w", err)
+	}
+	s.ServerFlags = ServerFlags(flags)
+
+	s.NumPlayers, err = nextIntString(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal number of players: %w", err)
+	}
+	s.MaxPlayers, err = nextIntString(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal max players: %w", err)
+	}
+	s.NumClients, err = nextIntString(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal number of clients: %w", err)
+	}
+	s.MaxClients, err = nextIntString(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal max clients: %w", err)
+	}
+
+	// extra info, reserved
+	_, err = u.NextString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal extra info: %w", err)
+	}
+
+	return unmarshalPlayersExtended(u)
+}
+
+// unmarshalPlayersExtended parses players until no data is left
+func unmarshalPlayersExtended(u *compression.Unpacker) (PlayerInfos, error) {
+	players := make(PlayerInfos, 0, 16)
+	for u.Size() > 0 {
+		var player PlayerInfo
+		err := player.unmarshalExtended(u)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, player)
+	}
+	return players, nil
+}
+
+func (p *PlayerInfo) marshalBinaryExtended() ([]byte, error) {
+	packer := compression.NewPacker(make([]byte, 0, 2+len(p.Name)+len(p.Clan)+3*5+1))
+
+	packer.AddString(p.Name)
+	packer.AddString(p.Clan)
+
+	packer.AddString(fmt.Sprint(int(p.Country)))
+	packer.AddString(fmt.Sprint(p.Score))
+
+	isPlayer := "1"
+	if p.Type.Spectator() {
+		isPlayer = "0"
+	}
+	packer.AddString(isPlayer)
+
+	extraInfo := ""
+	if p.Afk || p.Skin != nil {
+		data, err := json.Marshal(playerExtraInfo{
+			Afk:  p.Afk,
+			Skin: p.Skin,
+		})
+		if err != nil {
+			return nil, err
+		}
+		extraInfo = string(data)
+	}
+	packer.AddString(extraInfo)
+
+	return packer.Bytes(), nil
+}
+
+func (p *PlayerInfo) unmarshalExtended(u *compression.Unpacker) (err error) {
+	p.Name, err = u.NextString()
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal name: %w", err)
+	}
+	p.Clan, err = u.NextString()
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal clan: %w", err)
+	}
+
+	country, err := nextIntString(u)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal country code: %w", err)
+	}
+	p.Country = Country(country)
+	p.Score, err = nextIntString(u)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal score: %w", err)
+	}
+	isPlayer, err := nextIntString(u)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal player type: %w", err)
+	}
+	p.Type = 0
+	if isPlayer == 0 {
+		p.Type = PlayerTypeSpectator
+	}
+
+	extraInfo, err := u.NextRawString()
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal extra info: %w", err)
+	}
+	return p.unmarshalExtraInfo(extraInfo)
+}
+
+// playerExtraInfo is the per player extra info of the extended server info.
+// Current DDNet servers send an empty string, newer servers send a JSON object.
+type playerExtraInfo struct {
+	Afk  bool        `json:"afk"`
+	Skin *PlayerSkin `json:"skin,omitempty"`
+}
+
+func (p *PlayerInfo) unmarshalExtraInfo(extraInfo string) error {
+	if !strings.HasPrefix(extraInfo, "{") {
+		// reserved or unknown format
+		return nil
+	}
+	var ei playerExtraInfo
+	err := json.Unmarshal([]byte(extraInfo), &ei)
+	if err != nil {
+		return fmt.Errorf("%w: invalid extra info: %w", ErrMalformedResponseData, err)
+	}
+	p.Afk = ei.Afk
+	p.Skin = ei.Skin
+	return nil
+}
@@ -0,0 +1,142 @@
+package browser_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/compression"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// serverInfoDDNet creates a server info with many players that does not fit into a single packet
+func serverInfoDDNet(name string, numPlayers int) browser.ServerInfo {
+	info := serverInfo06(serverInfo(name))
+	info.Version = "0.6.4, 17.0"
+	info.GameType = "DDraceNetwork"
+	info.MaxPlayers = numPlayers
+	info.MaxClients = numPlayers
+
+	color := 0xff00
+	info.Players = make(browser.PlayerInfos, 0, numPlayers)
+	for i := 0; i < numPlayers; i++ {
+		player := browser.PlayerInfo{
+			Name:    fmt.Sprintf("tee %d", i),
+			Clan:    "DDNet",
+			Country: browser.Country(i),
+			Score:   -9999 + i,
+		}
+		if i%4 == 0 {
+			player.Type = browser.PlayerTypeSpectator
+		}
+		if i%3 == 0 {
+			player.Afk = true
+		}
+		if i%2 == 0 {
+			player.Skin = &browser.PlayerSkin{Name: "santa_limekitty", ColorBody: &color, ColorFeet: &color}
+		}
+		info.Players = append(info.Players, player)
+	}
+	info.NumClients = numPlayers
+	info.NumPlayers = numPlayers - (numPlayers+3)/4
+	return info
+}
+
+func TestMultiPartServerInfo(t *testing.T) {
+	t.Parallel()
+	expected := serverInfoDDNet("ddnet", 64)
+
+	parts, err := expected.MarshalBinaryExtended()
+	require.NoError(t, err)
+	require.Greater(t, 1, len(parts))
+
+	merger := browser.MultiPartServerInfo{}
+	// parts may arrive in any order
+	for idx := len(parts) - 1; idx >= 0; idx-- {
+		require.False(t, merger.Complete())
+
+		header := browser.SendInfoExtendedMore
+		if idx == 0 {
+			header = browser.SendInfoExtended
+		}
+		p := compression.NewPacker()
+		p.AddString("42") // request token
+		p.AddBytes(parts[idx])
+		resp := &browser.ResponsePacket{ResponseHeader: header, Payload: p.Bytes()}
+
+		require.NoError(t, merger.Add(resp))
+		// duplicates are ignored
+		require.NoError(t, merger.Add(resp))
+	}
+	require.True(t, merger.Complete())
+
+	info := merger.ServerInfo()
+	require.True(t, info.Equal(expected), "expected: %s, got: %s", expected.String(), info.String())
+}
+
+func TestClientGetServerInfoDDNet(t *testing.T) {
+	t.Parallel()
+	expected := serverInfoDDNet("ddnet", 64)
+	s := newServer(t, expected)
+	expected.Address = s.Addr
+
+	c, err := browser.NewClient(s.Addr)
+	require.NoError(t, err)
+	defer c.Close()
+	c.SetProtocol(browser.ProtocolDDNet)
+
+	info, err := c.GetServerInfo()
+	require.NoError(t, err)
+	require.Len(t, 64, info.Players)
+	require.True(t, info.Equal(expected), "expected: %s, got: %s", expected.String(), info.String())
+}
+
+func TestScannerDDNet(t *testing.T) {
+	t.Parallel()
+	small := serverInfoDDNet("small", 2)
+	large := serverInfoDDNet("large", 64)
+	s1 := newServer(t, small)
+	s2 := newServer(t, large)
+
+	s, err := browser.NewScanner(browser.WithProtocol(browser.ProtocolDDNet))
+	require.NoError(t, err)
+	defer s.Close()
+
+	infos, err := s.Scan(s1.Addr, s2.Addr)
+	require.NoError(t, err)
+	require.Len(t, 2, infos)
+	for _, info := range infos {
+		expected := small
+		if info.Address == s2.Addr {
+			expected = large
+		}
+		expected.Address = info.Address
+		require.True(t, info.Equal(expected), "expected: %s, got: %s", expected.String(), info.String())
+	}
+}
+
+func TestScannerDDNetMissingPart(t *testing.T) {
+	t.Parallel()
+	expected := serverInfoDDNet("large", 64)
+	srv := newServer(t, expected)
+	srv.SetFaults(browsertest.Faults{DropParts: []int{1}})
+
+	s, err := browser.NewScanner(
+		browser.WithProtocol(browser.ProtocolDDNet),
+		browser.WithRetries(1),
+		browser.WithRetryInterval(100*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer s.Close()
+
+	// the players of the received parts are returned after the last retry
+	infos, err := s.Scan(srv.Addr)
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+	require.Equal(t, "large", infos[0].Name)
+	require.Equal(t, 64, infos[0].NumClients)
+	require.Greater(t, 0, len(infos[0].Players))
+	require.Less(t, 64, len(infos[0].Players))
+}
@@ -0,0 +1,124 @@
+package browser
+
+// ScoreChange is the score change of a player that is on the server in both server infos
+type ScoreChange struct {
+	Player   PlayerInfo
+	OldScore int
+}
+
+// ServerInfoDiff lists the changes between two server infos of the same game server.
+// Players are identified by their name.
+type ServerInfoDiff struct {
+	Old ServerInfo
+	New ServerInfo
+
+	Joined       PlayerInfos
+	Left         PlayerInfos
+	ScoreChanges []ScoreChange
+}
+
+// Diff returns the changes from the server info s to the newer server info.
+func (s *ServerInfo) Diff(newer ServerInfo) ServerInfoDiff {
+	d := ServerInfoDiff{
+		Old: *s,
+		New: newer,
+	}
+
+	// player names are unique on a server, but we do not rely on that
+	old := make(map[string][]PlayerInfo, len(s.Players))
+	for _, p := range s.Players {
+		old[p.Name] = append(old[p.Name], p)
+	}
+
+	for _, p := range newer.Players {
+		candidates := old[p.Name]
+		if len(candidates) == 0 {
+			d.Joined = append(d.Joined, p)
+			continue
+		}
+		prev := candidates[0]
+		old[p.Name] = candidates[1:]
+
+		if prev.Score != p.Score {
+			d.ScoreChanges = append(d.ScoreChanges, ScoreChange{
+				Player:   p,
+				OldScore: prev.Score,
+			})
+		}
+	}
+
+	// keep the order of the old server info
+	for _, p := range s.Players {
+		candidates := old[p.Name]
+		if len(candidates) == 0 {
+			continue
+		}
+		d.Left = append(d.Left, candidates[0])
+		old[p.Name] = candidates[1:]
+	}
+	return d
+}
+
+// NameChanged returns true if the server name changed
+func (d *ServerInfoDiff) NameChanged() bool {
+	return d.Old.Name != d.New.Name
+}
+
+// MapChanged returns true if the map changed
+func (d *ServerInfoDiff) MapChanged() bool {
+	return d.Old.Map != d.New.Map
+}
+
+// GameTypeChanged returns true if the gametype changed
+func (d *ServerInfoDiff) GameTypeChanged() bool {
+	return d.Old.GameType != d.New.GameType
+}
+
+// FlagsChanged returns true if the server flags changed, e.g. a password was set
+func (d *ServerInfoDiff) FlagsChanged() bool {
+	return d.Old.ServerFlags != d.New.ServerFlags
+}
+
+// Empty returns true if none of the tracked properties changed
+func (d *ServerInfoDiff) Empty() bool {
+	return len(d.Joined) == 0 &&
+		len(d.Left) == 0 &&
+		len(d.ScoreChanges) == 0 &&
+		!d.NameChanged() &&
+		!d.MapChanged() &&
+		!d.GameTypeChanged() &&
+		!d.FlagsChanged()
+}
+
+// Events converts the diff into events.
+// Server changes are listed before the player changes, players leave before others join.
+func (d *ServerInfoDiff) Events() []Event {
+	address := d.New.Address
+	if address == "" {
+		address = d.Old.Address
+	}
+
+	events := make([]Event, 0, 4+len(d.Joined)+len(d.Left)+len(d.ScoreChanges))
+	if d.NameChanged() {
+		events = append(events, &NameChanged{Address: address, Old: d.Old.Name, New: d.New.Name})
+	}
+	if d.MapChanged() {
+		events = append(events, &MapChanged{Address: address, Old: d.Old.Map, New: d.New.Map})
+	}
+	if d.GameTypeChanged() {
+		events = append(events, &GameTypeChanged{Address: address, Old: d.Old.GameType, New: d.New.GameType})
+	}
+	if d.FlagsChanged() {
+		events = append(events, &FlagsChanged{Address: address, Old: d.Old.ServerFlags, New: d.New.ServerFlags})
+	}
+	for _, p := range d.Left {
+		events = append(events, &PlayerLeft{Address: address, Player: p})
+	}
+	for _, p := range d.Joined {
+		events = append(events, &PlayerJoined{Address: address, Player: p})
+	}
+	for _, c := range d.ScoreChanges {
+		events = append(events, &ScoreChanged{Address: address, Player: c.Player, OldScore: c.OldScore})
+	}
+	return events
+}
@@ -0,0 +1,51 @@
+package browser_test
+
+import (
+	"testing"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func TestServerInfoDiff(t *testing.T) {
+	t.Parallel()
+	old := serverInfo("old")
+	old.Address = "127.0.0.1:8303"
+
+	diff := old.Diff(old)
+	require.True(t, diff.Empty())
+	require.Len(t, 0, diff.Events())
+
+	newer := old
+	newer.Name = "new"
+	newer.Map = "ctf2"
+	newer.ServerFlags = 0
+	newer.Players = browser.PlayerInfos{
+		{Name: "nameless tee", Clan: "clan", Country: 276, Score: 13},
+		{Name: "brave tee", Country: 40, Score: 0},
+	}
+
+	diff = old.Diff(newer)
+	require.False(t, diff.Empty())
+	require.True(t, diff.NameChanged())
+	require.True(t, diff.MapChanged())
+	require.False(t, diff.GameTypeChanged())
+	require.True(t, diff.FlagsChanged())
+	require.Len(t, 1, diff.Joined)
+	require.Equal(t, "brave tee", diff.Joined[0].Name)
+	require.Len(t, 1, diff.Left)
+	require.Equal(t, "brainless tee", diff.Left[0].Name)
+	require.Len(t, 1, diff.ScoreChanges)
+	require.Equal(t, 12, diff.ScoreChanges[0].OldScore)
+	require.Equal(t, 13, diff.ScoreChanges[0].Player.Score)
+
+	events := diff.Events()
+	require.Len(t, 6, events)
+	for _, e := range events {
+		require.Equal(t, old.Address, e.ServerAddress())
+	}
+	mapChanged, ok := events[1].(*browser.MapChanged)
+	require.True(t, ok)
+	require.Equal(t, "ctf5", mapChanged.Old)
+	require.Equal(t, "ctf2", mapChanged.New)
+}
@@ -0,0 +1,131 @@
+// Package exporter periodically scans all game servers and exposes the results
+// as Prometheus metrics in the text exposition format.
+//
+//	e := exporter.New(time.Minute)
+//	err := e.ListenAndServe(ctx, "127.0.0.1:9150")
+//
+// All metrics are prefixed with teeworlds_. The per server metrics are labeled with the
+// address and the name of the game server, the aggregated player metrics with the gametype,
+// map and version.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// New creates an exporter that fetches the server infos of all game servers in the given interval.
+// The options are passed to browser.GetServerInfosContext.
+func New(interval time.Duration, options ...browser.ScannerOption) *Exporter {
+	return &Exporter{
+		interval: interval,
+		options:  options,
+	}
+}
+
+// Exporter exposes the result of the last scan as Prometheus metrics
+type Exporter struct {
+	interval time.Duration
+	options  []browser.ScannerOption
+
+	mu       sync.Mutex
+	infos    []browser.ServerInfo
+	scanned  bool
+	duration time.Duration
+	lastScan time.Time
+	scans    int
+	errors   int
+}
+
+// Run scans immediately and after every interval until the context is done.
+func (e *Exporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.Scan(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan fetches the server infos of all game servers once and updates the metrics.
+// In case the scan fails without any results, the previous server infos are kept.
+func (e *Exporter) Scan(ctx context.Context) {
+	start := time.Now()
+	infos, err := browser.GetServerInfosContext(ctx, e.options...)
+	duration := time.Since(start)
+	if err != nil && browser.Logging {
+		log.Printf("scan failed after %s: %v\n", duration, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scans++
+	e.duration = duration
+	e.lastScan = start.Add(duration)
+	if err != nil {
+		e.errors++
+	}
+	if err != nil && len(infos) == 0 {
+		return
+	}
+	e.infos = infos
+	e.scanned = true
+}
+
+// ServeHTTP writes the metrics of the last scan
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	e.WriteMetrics(&buf)
+
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// ListenAndServe serves the metrics at /metrics of the address and scans in the background
+// until the context is done.
+func (e *Exporter) ListenAndServe(ctx context.Context, address string) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = e.Run(runCtx)
+		_ = srv.Close()
+	}()
+
+	err = srv.Serve(l)
+	cancel()
+	wg.Wait()
+	if errors.Is(err, http.ErrServerClosed) {
+		return ctx.Err()
+	}
+	return err
+}
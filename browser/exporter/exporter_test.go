@@ -0,0 +1,81 @@
+package exporter_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/browser/exporter"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func newServer(t *testing.T, name, gameType string, players int) *browsertest.Server {
+	t.Helper()
+	info := browser.ServerInfo{
+		Version:    "0.7.5",
+		Name:       name,
+		Map:        "ctf5",
+		GameType:   gameType,
+		MaxPlayers: 16,
+		MaxClients: 16,
+	}
+	for i := 0; i < players; i++ {
+		info.Players = append(info.Players, browser.PlayerInfo{Name: strings.Repeat("x", i+1)})
+	}
+	info.NumPlayers = players
+	info.NumClients = players
+
+	s, err := browsertest.NewServer(info)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+	return s
+}
+
+func TestExporter(t *testing.T) {
+	t.Parallel()
+	ctf1 := newServer(t, "ctf 1", "CTF", 2)
+	ctf2 := newServer(t, `the "best" ctf`, "CTF", 3)
+	dm := newServer(t, "dm", "DM", 1)
+
+	m, err := browsertest.NewMaster(
+		netip.MustParseAddrPort(ctf1.Addr),
+		netip.MustParseAddrPort(ctf2.Addr),
+		netip.MustParseAddrPort(dm.Addr),
+	)
+	require.NoError(t, err)
+	defer m.Close()
+
+	e := exporter.New(time.Minute, browser.WithSources(browser.NewMasterSource(browser.Protocol07, m.Addr)))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, exporter.ContentType, rec.Header().Get("Content-Type"))
+	require.True(t, strings.Contains(rec.Body.String(), "teeworlds_scans_total 0\n"), rec.Body.String())
+	require.False(t, strings.Contains(rec.Body.String(), "teeworlds_servers"), rec.Body.String())
+
+	e.Scan(context.Background())
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, expected := range []string{
+		"# TYPE teeworlds_servers gauge\nteeworlds_servers 3\n",
+		"teeworlds_scans_total 1\n",
+		"teeworlds_scan_errors_total 0\n",
+		"# TYPE teeworlds_scan_duration_seconds gauge\n",
+		`teeworlds_players{gametype="CTF",map="ctf5",version="0.7.5"} 5` + "\n",
+		`teeworlds_players{gametype="DM",map="ctf5",version="0.7.5"} 1` + "\n",
+		`teeworlds_server_players{address="` + ctf1.Addr + `",name="ctf 1"} 2` + "\n",
+		`teeworlds_server_players{address="` + ctf2.Addr + `",name="the \"best\" ctf"} 3` + "\n",
+		`teeworlds_server_max_clients{address="` + dm.Addr + `",name="dm"} 16` + "\n",
+	} {
+		require.True(t, strings.Contains(body, expected), expected)
+	}
+}
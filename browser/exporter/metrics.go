@@ -0,0 +1,195 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// label is a name="value" pair of a sample
+type label struct {
+	name  string
+	value string
+}
+
+// sample is a single line of a metric
+type sample struct {
+	labels []label
+	value  float64
+}
+
+// metric is a metric family in the text exposition format
+type metric struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+// WriteMetrics writes all metrics of the last scan in the text exposition format
+func (e *Exporter) WriteMetrics(w io.Writer) error {
+	for _, m := range e.metrics() {
+		err := m.write(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) metrics() []metric {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	metrics := []metric{
+		{
+			name:    "teeworlds_scans_total",
+			help:    "Number of scans of all game servers.",
+			typ:     "counter",
+			samples: []sample{{value: float64(e.scans)}},
+		},
+		{
+			name:    "teeworlds_scan_errors_total",
+			help:    "Number of scans that failed.",
+			typ:     "counter",
+			samples: []sample{{value: float64(e.errors)}},
+		},
+	}
+	if e.scans == 0 {
+		return metrics
+	}
+	metrics = append(metrics,
+		metric{
+			name:    "teeworlds_scan_duration_seconds",
+			help:    "Duration of the last scan.",
+			typ:     "gauge",
+			samples: []sample{{value: e.duration.Seconds()}},
+		},
+		metric{
+			name:    "teeworlds_last_scan_timestamp_seconds",
+			help:    "Unix time of the end of the last scan.",
+			typ:     "gauge",
+			samples: []sample{{value: float64(e.lastScan.UnixMilli()) / 1000}},
+		},
+	)
+	if !e.scanned {
+		return metrics
+	}
+
+	type group struct {
+		gameType string
+		mapName  string
+		version  string
+	}
+	var (
+		players    = make(map[group]int)
+		clients    = make(map[group]int)
+		perPlayers = make([]sample, 0, len(e.infos))
+		perClients = make([]sample, 0, len(e.infos))
+		perMax     = make([]sample, 0, len(e.infos))
+	)
+	for _, info := range e.infos {
+		g := group{info.GameType, info.Map, info.Version}
+		players[g] += info.NumPlayers
+		clients[g] += info.NumClients
+
+		labels := []label{{"address", info.Address}, {"name", info.Name}}
+		perPlayers = append(perPlayers, sample{labels, float64(info.NumPlayers)})
+		perClients = append(perClients, sample{labels, float64(info.NumClients)})
+		perMax = append(perMax, sample{labels, float64(info.MaxClients)})
+	}
+
+	groupSamples := func(values map[group]int) []sample {
+		samples := make([]sample, 0, len(values))
+		for g, n := range values {
+			samples = append(samples, sample{
+				labels: []label{{"gametype", g.gameType}, {"map", g.mapName}, {"version", g.version}},
+				value:  float64(n),
+			})
+		}
+		return samples
+	}
+
+	return append(metrics,
+		metric{
+			name:    "teeworlds_servers",
+			help:    "Number of game servers that responded to the last scan.",
+			typ:     "gauge",
+			samples: []sample{{value: float64(len(e.infos))}},
+		},
+		metric{
+			name:    "teeworlds_players",
+			help:    "Number of ingame players by gametype, map and version.",
+			typ:     "gauge",
+			samples: groupSamples(players),
+		},
+		metric{
+			name:    "teeworlds_clients",
+			help:    "Number of players and spectators by gametype, map and version.",
+			typ:     "gauge",
+			samples: groupSamples(clients),
+		},
+		metric{
+			name:    "teeworlds_server_players",
+			help:    "Number of ingame players of a game server.",
+			typ:     "gauge",
+			samples: perPlayers,
+		},
+		metric{
+			name:    "teeworlds_server_clients",
+			help:    "Number of players and spectators of a game server.",
+			typ:     "gauge",
+			samples: perClients,
+		},
+		metric{
+			name:    "teeworlds_server_max_clients",
+			help:    "Maximum number of players and spectators of a game server.",
+			typ:     "gauge",
+			samples: perMax,
+		},
+	)
+}
+
+// write writes the metric family, the samples are sorted by their labels
+func (m *metric) write(w io.Writer) error {
+	lines := make([]string, 0, len(m.samples))
+	for _, s := range m.samples {
+		lines = append(lines, m.name+formatLabels(s.labels)+" "+strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+	sort.Strings(lines)
+
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		_, err = io.WriteString(w, line+"\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatLabels(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for idx, l := range labels {
+		if idx > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(l.name)
+		sb.WriteString(`="`)
+		sb.WriteString(labelEscaper.Replace(l.value))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,264 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+const (
+	// AddressFamilyAny uses IPv4 as well as IPv6
+	AddressFamilyAny AddressFamily = iota
+	// AddressFamilyIPv4 only uses IPv4
+	AddressFamilyIPv4
+	// AddressFamilyIPv6 only uses IPv6
+	AddressFamilyIPv6
+)
+
+// AddressFamily restricts the IP addresses that are used for scans
+type AddressFamily int
+
+func (f AddressFamily) String() string {
+	switch f {
+	case AddressFamilyIPv4:
+		return "ipv4"
+	case AddressFamilyIPv6:
+		return "ipv6"
+	default:
+		return "any"
+	}
+}
+
+// Contains returns true if the address belongs to the address family.
+// IPv4-mapped IPv6 addresses are IPv4 addresses.
+func (f AddressFamily) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	switch f {
+	case AddressFamilyIPv4:
+		return addr.Is4()
+	case AddressFamilyIPv6:
+		return addr.Is6()
+	default:
+		return addr.IsValid()
+	}
+}
+
+// network returns the network name of the net package
+func (f AddressFamily) network() string {
+	switch f {
+	case AddressFamilyIPv4:
+		return "udp4"
+	case AddressFamilyIPv6:
+		return "udp6"
+	default:
+		return "udp"
+	}
+}
+
+// familyOf returns the address family of a single address
+func familyOf(addr netip.Addr) AddressFamily {
+	if addr.Unmap().Is4() {
+		return AddressFamilyIPv4
+	}
+	return AddressFamilyIPv6
+}
+
+// resolveUDPAddr resolves host names to an address of the family.
+// IP addresses of another family result in ErrAddressFamily.
+func (f AddressFamily) resolveUDPAddr(address string) (*net.UDPAddr, error) {
+	if addr, err := netip.ParseAddrPort(address); err == nil && !f.Contains(addr.Addr()) {
+		return nil, fmt.Errorf("%w: %s is not %s", ErrAddressFamily, address, f)
+	}
+	return net.ResolveUDPAddr(f.network(), address)
+}
+
+// localUDPAddr returns the address family and the local address the scanner binds its socket to.
+// A local address restricts the address family to the family of the local address.
+func (s *Scanner) localUDPAddr() (AddressFamily, *net.UDPAddr, error) {
+	switch {
+	case s.localAddress != "":
+		addr, err := netip.ParseAddrPort(s.localAddress)
+		if err != nil {
+			ip, ipErr := netip.ParseAddr(s.localAddress)
+			if ipErr != nil {
+				return s.family, nil, fmt.Errorf("invalid local address %q: %w", s.localAddress, err)
+			}
+			addr = netip.AddrPortFrom(ip, 0)
+		}
+		if !s.family.Contains(addr.Addr()) {
+			return s.family, nil, fmt.Errorf("%w: local address %s is not %s", ErrAddressFamily, s.localAddress, s.family)
+		}
+		return familyOf(addr.Addr()), net.UDPAddrFromAddrPort(addr), nil
+	case s.iface != "":
+		iface, err := net.InterfaceByName(s.iface)
+		if err != nil {
+			return s.family, nil, err
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return s.family, nil, err
+		}
+		// IPv4 is preferred, as IPv6 link local addresses cannot reach any game servers
+		var candidate *netip.Addr
+		for _, a := range addrs {
+			prefix, err := netip.ParsePrefix(a.String())
+			if err != nil {
+				continue
+			}
+			addr := prefix.Addr()
+			if !s.family.Contains(addr) || addr.IsLinkLocalUnicast() {
+				continue
+			}
+			if addr.Is4() {
+				candidate = &addr
+				break
+			}
+			if candidate == nil {
+				candidate = &addr
+			}
+		}
+		if candidate == nil {
+			return s.family, nil, fmt.Errorf("%w: interface %s has no %s address", ErrAddressFamily, s.iface, s.family)
+		}
+		return familyOf(*candidate), &net.UDPAddr{IP: candidate.AsSlice()}, nil
+	default:
+		return s.family, nil, nil
+	}
+}
+
+// filterServerList removes all addresses that do not belong to the address family.
+// Server infos that are reachable with another address of the family keep that address.
+func filterServerList(list ServerList, family AddressFamily) ServerList {
+	if family == AddressFamilyAny {
+		return list
+	}
+
+	result := ServerList{
+		Addresses: make([]netip.AddrPort, 0, len(list.Addresses)),
+		Infos:     make([]ServerInfo, 0, len(list.Infos)),
+	}
+	for _, addr := range list.Addresses {
+		if family.Contains(addr.Addr()) {
+			result.Addresses = append(result.Addresses, addr)
+		}
+	}
+	for _, info := range list.Infos {
+		endpoints := make([]string, 0, 2)
+		for _, address := range info.endpoints() {
+			addr, err := netip.ParseAddrPort(address)
+			if err == nil && family.Contains(addr.Addr()) {
+				endpoints = append(endpoints, address)
+			}
+		}
+		if len(endpoints) == 0 {
+			continue
+		}
+		info.setEndpoints(endpoints)
+		result.Infos = append(result.Infos, info)
+	}
+	return result
+}
+
+// endpoints returns all addresses of the server
+func (s *ServerInfo) endpoints() []string {
+	if len(s.Addresses) > 0 {
+		return s.Addresses
+	}
+	return []string{s.Address}
+}
+
+// setEndpoints sets the first endpoint as address and all of them as addresses
+// in case there is more than one
+func (s *ServerInfo) setEndpoints(endpoints []string) {
+	s.Address = endpoints[0]
+	s.Addresses = nil
+	if len(endpoints) > 1 {
+		s.Addresses = endpoints
+	}
+}
+
+// GroupServerInfos merges the server infos of game servers that are reachable via IPv4 and IPv6
+// into a single server info. The IPv4 address is used as Address and all addresses are listed
+// in Addresses. Server infos are considered to belong to the same game server if they only
+// differ in their IP address and latency.
+// The order of the server infos is kept.
+func GroupServerInfos(infos []ServerInfo) []ServerInfo {
+	var (
+		result = make([]ServerInfo, 0, len(infos))
+		// indices of the result that might be grouped with other server infos
+		groups = make(map[string][]int, len(infos))
+	)
+
+next:
+	for _, info := range infos {
+		addr, err := netip.ParseAddrPort(info.Address)
+		if err != nil {
+			result = append(result, info)
+			continue
+		}
+		key := info.groupKey(addr.Port())
+		family := familyOf(addr.Addr())
+
+		for _, idx := range groups[key] {
+			if result[idx].hasFamily(family) {
+				continue
+			}
+			result[idx] = mergeServerInfos(result[idx], info)
+			continue next
+		}
+		groups[key] = append(groups[key], len(result))
+		result = append(result, info)
+	}
+	return result
+}
+
+// groupKey identifies the game server independent of its IP address.
+// Only server infos with the same port are grouped.
+func (s ServerInfo) groupKey(port uint16) string {
+	s.Address = ""
+	s.Addresses = nil
+	s.Latency = nil
+	data, _ := json.Marshal(s)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d:", port)
+	sb.Write(data)
+	return sb.String()
+}
+
+// hasFamily returns true if the server has an address of the address family
+func (s *ServerInfo) hasFamily(family AddressFamily) bool {
+	for _, address := range s.endpoints() {
+		addr, err := netip.ParseAddrPort(address)
+		if err == nil && family.Contains(addr.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeServerInfos adds the addresses of b to a.
+// The server info with the IPv4 address is kept as primary one.
+func mergeServerInfos(a, b ServerInfo) ServerInfo {
+	endpoints := append(slices.Clone(a.endpoints()), b.endpoints()...)
+	if b.hasFamily(AddressFamilyIPv4) {
+		a = b
+	}
+	// IPv4 first, the order within a family is kept
+	slices.SortStableFunc(endpoints, func(x, y string) int {
+		return isIPv6(x) - isIPv6(y)
+	})
+	a.setEndpoints(endpoints)
+	return a
+}
+
+func isIPv6(address string) int {
+	addr, err := netip.ParseAddrPort(address)
+	if err == nil && familyOf(addr.Addr()) == AddressFamilyIPv6 {
+		return 1
+	}
+	return 0
+}
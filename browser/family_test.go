@@ -0,0 +1,123 @@
+package browser_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// newDualStackServers starts the same fake game server on 127.0.0.1 and ::1 with the same port
+func newDualStackServers(t *testing.T, info browser.ServerInfo) (v4, v6 *browsertest.Server) {
+	t.Helper()
+	v4 = newServer(t, info)
+	port := addrPort(t, v4.Addr).Port()
+
+	v6, err := browsertest.NewServerOn(netip.AddrPortFrom(netip.IPv6Loopback(), port), info)
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = v6.Close()
+	})
+	return v4, v6
+}
+
+func TestScannerAddressFamily(t *testing.T) {
+	t.Parallel()
+	v4, v6 := newDualStackServers(t, serverInfo("dual stack"))
+
+	for _, test := range []struct {
+		options  []browser.ScannerOption
+		online   string
+		rejected string
+	}{
+		{[]browser.ScannerOption{browser.WithAddressFamily(browser.AddressFamilyIPv4)}, v4.Addr, v6.Addr},
+		{[]browser.ScannerOption{browser.WithAddressFamily(browser.AddressFamilyIPv6)}, v6.Addr, v4.Addr},
+		{[]browser.ScannerOption{browser.WithLocalAddress("127.0.0.1")}, v4.Addr, v6.Addr},
+		{[]browser.ScannerOption{browser.WithLocalAddress("::1")}, v6.Addr, v4.Addr},
+	} {
+		s, err := browser.NewScanner(test.options...)
+		require.NoError(t, err)
+
+		results := map[string]browser.ScanResult{}
+		for result := range s.Stream(context.Background(), v4.Addr, v6.Addr) {
+			results[result.Address] = result
+		}
+		require.NoError(t, s.Close())
+
+		require.NoError(t, results[test.online].Err, test.online)
+		require.Equal(t, "dual stack", results[test.online].Info.Name)
+		require.ErrorIs(t, browser.ErrAddressFamily, results[test.rejected].Err, test.rejected)
+	}
+
+	_, err := browser.NewScanner(
+		browser.WithAddressFamily(browser.AddressFamilyIPv6),
+		browser.WithLocalAddress("127.0.0.1"),
+	)
+	require.ErrorIs(t, browser.ErrAddressFamily, err)
+}
+
+func TestGetServerInfosGrouped(t *testing.T) {
+	t.Parallel()
+	v4, v6 := newDualStackServers(t, serverInfo("dual stack"))
+	other := newServer(t, serverInfo("ipv4 only"))
+	m := newMaster(t, addrPort(t, v6.Addr), addrPort(t, v4.Addr), addrPort(t, other.Addr))
+	source := browser.WithSources(browser.NewMasterSource(browser.Protocol07, m.Addr))
+
+	infos, err := browser.GetServerInfosContext(context.Background(), source, browser.WithGroupedAddresses())
+	require.NoError(t, err)
+	require.Len(t, 2, infos)
+	for _, info := range infos {
+		if info.Name == "dual stack" {
+			require.Equal(t, v4.Addr, info.Address)
+			require.Equal(t, []string{v4.Addr, v6.Addr}, info.Addresses)
+		} else {
+			require.Equal(t, other.Addr, info.Address)
+			require.Len(t, 0, info.Addresses)
+		}
+	}
+
+	infos, err = browser.GetServerInfosContext(context.Background(), source, browser.WithAddressFamily(browser.AddressFamilyIPv6))
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+	require.Equal(t, v6.Addr, infos[0].Address)
+}
+
+func TestGroupServerInfos(t *testing.T) {
+	t.Parallel()
+
+	info := func(name, address string) browser.ServerInfo {
+		info := serverInfo(name)
+		info.Address = address
+		return info
+	}
+	infos := []browser.ServerInfo{
+		info("a", "[2001:db8::1]:8303"),
+		info("a", "10.0.0.1:8303"),
+		// another server with the same name and port
+		info("a", "10.0.0.2:8303"),
+		// same name on another port
+		info("a", "[2001:db8::1]:8304"),
+		info("b", "[2001:db8::2]:8303"),
+		info("c", "invalid"),
+	}
+	infos[1].Latency = &browser.Latency{Samples: 1}
+
+	grouped := browser.GroupServerInfos(infos)
+	require.Len(t, 5, grouped)
+
+	require.Equal(t, "10.0.0.1:8303", grouped[0].Address)
+	require.Equal(t, []string{"10.0.0.1:8303", "[2001:db8::1]:8303"}, grouped[0].Addresses)
+	// the IPv4 server info is kept
+	require.NotNil(t, grouped[0].Latency)
+
+	require.Equal(t, "10.0.0.2:8303", grouped[1].Address)
+	require.Len(t, 0, grouped[1].Addresses)
+	require.Equal(t, "[2001:db8::1]:8304", grouped[2].Address)
+	require.Equal(t, "[2001:db8::2]:8303", grouped[3].Address)
+	require.Equal(t, "invalid", grouped[4].Address)
+}
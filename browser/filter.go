@@ -0,0 +1,312 @@
+package browser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a predicate on server infos.
+// Filters can be composed with FilterAnd, FilterOr and FilterNot or parsed from a query with ParseFilter.
+type Filter func(info *ServerInfo) bool
+
+// Match returns true if the server info satisfies the filter.
+// A nil filter matches all server infos.
+func (f Filter) Match(info *ServerInfo) bool {
+	return f == nil || f(info)
+}
+
+// Apply returns all server infos that satisfy the filter, keeping their order
+func (f Filter) Apply(infos []ServerInfo) []ServerInfo {
+	result := make([]ServerInfo, 0, len(infos))
+	for idx := range infos {
+		if f.Match(&infos[idx]) {
+			result = append(result, infos[idx])
+		}
+	}
+	return result
+}
+
+// FilterAnd matches if all filters match
+func FilterAnd(filters ...Filter) Filter {
+	return func(info *ServerInfo) bool {
+		for _, f := range filters {
+			if !f.Match(info) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// FilterOr matches if at least one of the filters matches
+func FilterOr(filters ...Filter) Filter {
+	return func(info *ServerInfo) bool {
+		for _, f := range filters {
+			if f.Match(info) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterNot inverts the filter
+func FilterNot(f Filter) Filter {
+	return func(info *ServerInfo) bool {
+		return !f.Match(info)
+	}
+}
+
+// FilterGameType matches the gametype, ignoring the case
+func FilterGameType(gameType string) Filter {
+	return func(info *ServerInfo) bool {
+		return strings.EqualFold(info.GameType, gameType)
+	}
+}
+
+// FilterMapName matches the map name, ignoring the case
+func FilterMapName(name string) Filter {
+	return func(info *ServerInfo) bool {
+		return strings.EqualFold(info.Map, name)
+	}
+}
+
+// FilterNameContains matches servers whose name contains the passed string, ignoring the case
+func FilterNameContains(contains string) Filter {
+	contains = strings.ToLower(contains)
+	return func(info *ServerInfo) bool {
+		return strings.Contains(strings.ToLower(info.Name), contains)
+	}
+}
+
+// FilterHasPlayer matches servers with a player or spectator whose name contains the passed string, ignoring the case
+func FilterHasPlayer(contains string) Filter {
+	contains = strings.ToLower(contains)
+	return func(info *ServerInfo) bool {
+		for _, p := range info.Players {
+			if strings.Contains(strings.ToLower(p.Name), contains) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterVersionPrefix matches servers whose version starts with the prefix
+func FilterVersionPrefix(prefix string) Filter {
+	return func(info *ServerInfo) bool {
+		return strings.HasPrefix(info.Version, prefix)
+	}
+}
+
+// FilterEmpty matches servers without any players or spectators
+func FilterEmpty() Filter {
+	return func(info *ServerInfo) bool {
+		return info.NumClients == 0
+	}
+}
+
+// FilterFull matches servers that do not accept any further clients
+func FilterFull() Filter {
+	return func(info *ServerInfo) bool {
+		return info.NumClients >= info.MaxClients
+	}
+}
+
+// FilterPassword matches servers that require a password
+func FilterPassword() Filter {
+	return func(info *ServerInfo) bool {
+		return info.ServerFlags.Password()
+	}
+}
+
+// FilterPlayers matches the number of players, e.g. FilterPlayers(">", 4)
+func FilterPlayers(op string, n int) Filter {
+	return compareFilter(op, n, func(info *ServerInfo) (int, bool) {
+		return info.NumPlayers, true
+	})
+}
+
+// FilterClients matches the number of players and spectators, e.g. FilterClients("<=", 8)
+func FilterClients(op string, n int) Filter {
+	return compareFilter(op, n, func(info *ServerInfo) (int, bool) {
+		return info.NumClients, true
+	})
+}
+
+// FilterPing matches the average latency in milliseconds, e.g. FilterPing("<", 50).
+// Servers without a measured latency never match.
+func FilterPing(op string, ms int) Filter {
+	return compareFilter(op, ms, func(info *ServerInfo) (int, bool) {
+		if info.Latency == nil {
+			return 0, false
+		}
+		return int(info.Latency.Avg / time.Millisecond), true
+	})
+}
+
+func compareFilter(op string, n int, value func(info *ServerInfo) (int, bool)) Filter {
+	return func(info *ServerInfo) bool {
+		v, ok := value(info)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "<":
+			return v < n
+		case "<=":
+			return v <= n
+		case ">":
+			return v > n
+		case ">=":
+			return v >= n
+		case "=", "==":
+			return v == n
+		case "!=":
+			return v != n
+		default:
+			return false
+		}
+	}
+}
+
+// ParseFilter parses a query like `gametype:ctf players>4 !password` into a filter.
+// All terms of the query must match. Every term can be negated with a leading '!'.
+// Values that contain spaces can be quoted like `name:"my server"`.
+//
+// Supported terms:
+//
+//	gametype:<gametype>   map:<map>         name:<substring>
+//	player:<substring>    version:<prefix>
+//	players<op><n>        clients<op><n>    ping<op><ms>
+//	empty                 full              password
+//
+// The comparison operators are <, <=, >, >=, = and !=.
+func ParseFilter(query string) (Filter, error) {
+	terms, err := splitQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := make([]Filter, 0, len(terms))
+	for _, term := range terms {
+		f, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return FilterAnd(filters...), nil
+}
+
+// MustParseFilter is like ParseFilter but panics if the query cannot be parsed
+func MustParseFilter(query string) Filter {
+	f, err := ParseFilter(query)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+var (
+	valueFilters = map[string]func(string) Filter{
+		"gametype": FilterGameType,
+		"map":      FilterMapName,
+		"name":     FilterNameContains,
+		"player":   FilterHasPlayer,
+		"version":  FilterVersionPrefix,
+	}
+	flagFilters = map[string]func() Filter{
+		"empty":    FilterEmpty,
+		"full":     FilterFull,
+		"password": FilterPassword,
+	}
+	compareFilters = map[string]func(string, int) Filter{
+		"players": FilterPlayers,
+		"clients": FilterClients,
+		"ping":    FilterPing,
+	}
+)
+
+func parseTerm(term string) (Filter, error) {
+	if strings.HasPrefix(term, "!") {
+		f, err := parseTerm(term[1:])
+		if err != nil {
+			return nil, err
+		}
+		return FilterNot(f), nil
+	}
+
+	if key, value, ok := strings.Cut(term, ":"); ok {
+		newFilter, ok := valueFilters[strings.ToLower(key)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidQuery, key)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("%w: missing value of %q", ErrInvalidQuery, key)
+		}
+		return newFilter(value), nil
+	}
+
+	if idx := strings.IndexAny(term, "<>=!"); idx > 0 {
+		key, rest := strings.ToLower(term[:idx]), term[idx:]
+		newFilter, ok := compareFilters[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidQuery, key)
+		}
+		op := rest[:1]
+		if len(rest) > 1 && rest[1] == '=' {
+			op = rest[:2]
+		}
+		if op == "!" {
+			return nil, fmt.Errorf("%w: invalid operator in %q", ErrInvalidQuery, term)
+		}
+		n, err := strconv.Atoi(rest[len(op):])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number in %q", ErrInvalidQuery, term)
+		}
+		return newFilter(op, n), nil
+	}
+
+	newFilter, ok := flagFilters[strings.ToLower(term)]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown term %q", ErrInvalidQuery, term)
+	}
+	return newFilter(), nil
+}
+
+// splitQuery splits the query at whitespaces that are not quoted and removes the quotes
+func splitQuery(query string) ([]string, error) {
+	var (
+		terms  []string
+		term   strings.Builder
+		quoted bool
+		inTerm bool
+	)
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inTerm = true
+		case unicode.IsSpace(r) && !quoted:
+			if inTerm {
+				terms = append(terms, term.String())
+				term.Reset()
+				inTerm = false
+			}
+		default:
+			term.WriteRune(r)
+			inTerm = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidQuery)
+	}
+	if inTerm {
+		terms = append(terms, term.String())
+	}
+	return terms, nil
+}
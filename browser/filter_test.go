@@ -0,0 +1,106 @@
+package browser_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// filterInfos creates server infos that differ in every filtered property
+func filterInfos() []browser.ServerInfo {
+	ctf := serverInfo("CTF Server")
+	ctf.Address = "ctf"
+	ctf.NumPlayers = 5
+	ctf.ServerFlags = 0
+	ctf.Latency = &browser.Latency{Avg: 30 * time.Millisecond}
+
+	locked := serverInfo("Locked Server")
+	locked.Address = "locked"
+	locked.ServerFlags = 1
+
+	empty := serverInfo("empty server")
+	empty.Address = "empty"
+	empty.Version = "0.6.4"
+	empty.GameType = "DM"
+	empty.Map = "dm1"
+	empty.ServerFlags = 0
+	empty.NumPlayers = 0
+	empty.NumClients = 0
+	empty.Players = nil
+
+	full := serverInfo("full server")
+	full.Address = "full"
+	full.GameType = "DM"
+	full.ServerFlags = 0
+	full.MaxClients = full.NumClients
+	return []browser.ServerInfo{ctf, locked, empty, full}
+}
+
+func addresses(infos []browser.ServerInfo) []string {
+	result := make([]string, 0, len(infos))
+	for _, info := range infos {
+		result = append(result, info.Address)
+	}
+	return result
+}
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+	infos := filterInfos()
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"ctf", "locked", "empty", "full"}},
+		{"gametype:ctf players>4 !password", []string{"ctf"}},
+		{"gametype:CTF", []string{"ctf", "locked"}},
+		{"password", []string{"locked"}},
+		{"!empty !full", []string{"ctf", "locked"}},
+		{"map:dm1", []string{"empty"}},
+		{`name:"server" version:0.7`, []string{"ctf", "locked", "full"}},
+		{`name:"locked server"`, []string{"locked"}},
+		{"player:BRAIN", []string{"ctf", "locked", "full"}},
+		{"clients<=0", []string{"empty"}},
+		{"players!=1 players>=5", []string{"ctf"}},
+		{"ping<50", []string{"ctf"}},
+		{"!ping<50", []string{"locked", "empty", "full"}},
+	}
+	for _, test := range tests {
+		f, err := browser.ParseFilter(test.query)
+		require.NoError(t, err, test.query)
+		require.Equal(t, test.expected, addresses(f.Apply(infos)), test.query)
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	t.Parallel()
+	for _, query := range []string{
+		"unknown",
+		"mod:ctf",
+		"gametype:",
+		"players>many",
+		"players!4",
+		"score>4",
+		`name:"unterminated`,
+	} {
+		_, err := browser.ParseFilter(query)
+		require.ErrorIs(t, browser.ErrInvalidQuery, err, query)
+	}
+}
+
+func TestFilterComposition(t *testing.T) {
+	t.Parallel()
+	infos := filterInfos()
+
+	f := browser.FilterOr(
+		browser.FilterAnd(browser.FilterGameType("dm"), browser.FilterFull()),
+		browser.FilterNot(browser.FilterOr(browser.FilterPassword(), browser.FilterGameType("dm"))),
+	)
+	require.Equal(t, []string{"ctf", "full"}, addresses(f.Apply(infos)))
+
+	var all browser.Filter
+	require.Len(t, len(infos), all.Apply(infos))
+}
@@ -0,0 +1,193 @@
+package browser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ServerFlagPassword is set if the server requires a password
+	ServerFlagPassword ServerFlags = 1 << iota
+	// ServerFlagTimeScore is set if the score of the players is their race time
+	ServerFlagTimeScore
+)
+
+// ServerFlags are the bit flags of a ServerInfo
+type ServerFlags byte
+
+var serverFlagNames = []string{
+	"password",
+	"timescore",
+}
+
+// Password returns true if the server requires a password
+func (f ServerFlags) Password() bool {
+	return f&ServerFlagPassword != 0
+}
+
+// String returns the names of the set flags separated by '|', e.g. "password|timescore".
+// Unknown flags are returned as number.
+func (f ServerFlags) String() string {
+	return flagsString(int(f), serverFlagNames)
+}
+
+// MarshalText encodes the flags like String
+func (f ServerFlags) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText decodes the flags that were encoded with MarshalText
+func (f *ServerFlags) UnmarshalText(text []byte) error {
+	flags, err := parseFlags(string(text), serverFlagNames)
+	if err != nil {
+		return fmt.Errorf("%w: server flags %q", ErrUnknownValue, text)
+	}
+	*f = ServerFlags(flags)
+	return nil
+}
+
+// skill levels of the ServerInfo
+const (
+	SkillLevelCasual SkillLevel = iota
+	SkillLevelNormal
+	SkillLevelCompetitive
+)
+
+// SkillLevel is the skill level of the players that the server is meant for
+type SkillLevel byte
+
+var skillLevelNames = []string{
+	"casual",
+	"normal",
+	"competitive",
+}
+
+// String returns the name of the skill level, e.g. "normal". Unknown levels are returned as number.
+func (l SkillLevel) String() string {
+	if int(l) < len(skillLevelNames) {
+		return skillLevelNames[l]
+	}
+	return strconv.Itoa(int(l))
+}
+
+// MarshalText encodes the skill level like String
+func (l SkillLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes the skill level that was encoded with MarshalText
+func (l *SkillLevel) UnmarshalText(text []byte) error {
+	if idx := indexFold(skillLevelNames, string(text)); idx >= 0 {
+		*l = SkillLevel(idx)
+		return nil
+	}
+	level, err := strconv.ParseUint(string(text), 10, 8)
+	if err != nil {
+		return fmt.Errorf("%w: skill level %q", ErrUnknownValue, text)
+	}
+	*l = SkillLevel(level)
+	return nil
+}
+
+const (
+	// PlayerTypePlayer is an ingame player
+	PlayerTypePlayer PlayerType = 0
+	// PlayerTypeSpectator is set for spectators
+	PlayerTypeSpectator PlayerType = 1 << (iota - 1)
+	// PlayerTypeBot is set for clients that are controlled by the server
+	PlayerTypeBot
+)
+
+// PlayerType are the bit flags of a PlayerInfo
+type PlayerType int
+
+var playerTypeNames = []string{
+	"spectator",
+	"bot",
+}
+
+// Spectator returns true if the client is not playing
+func (t PlayerType) Spectator() bool {
+	return t&PlayerTypeSpectator != 0
+}
+
+// Bot returns true if the client is controlled by the server
+func (t PlayerType) Bot() bool {
+	return t&PlayerTypeBot != 0
+}
+
+// String returns "player" or the names of the set flags separated by '|', e.g. "spectator|bot".
+// Unknown flags are returned as number.
+func (t PlayerType) String() string {
+	if t == PlayerTypePlayer {
+		return "player"
+	}
+	return flagsString(int(t), playerTypeNames)
+}
+
+// MarshalText encodes the player type like String
+func (t PlayerType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes the player type that was encoded with MarshalText
+func (t *PlayerType) UnmarshalText(text []byte) error {
+	if strings.EqualFold(string(text), "player") {
+		*t = PlayerTypePlayer
+		return nil
+	}
+	flags, err := parseFlags(string(text), playerTypeNames)
+	if err != nil {
+		return fmt.Errorf("%w: player type %q", ErrUnknownValue, text)
+	}
+	*t = PlayerType(flags)
+	return nil
+}
+
+// flagsString joins the names of the set bits, names[i] is the name of the bit 1<<i.
+// The remaining unknown bits are appended as number.
+func flagsString(flags int, names []string) string {
+	parts := make([]string, 0, len(names)+1)
+	for idx, name := range names {
+		bit := 1 << idx
+		if flags&bit != 0 {
+			parts = append(parts, name)
+			flags &^= bit
+		}
+	}
+	if flags != 0 {
+		parts = append(parts, strconv.Itoa(flags))
+	}
+	return strings.Join(parts, "|")
+}
+
+// parseFlags is the inverse of flagsString
+func parseFlags(text string, names []string) (int, error) {
+	flags := 0
+	if text == "" {
+		return flags, nil
+	}
+	for _, part := range strings.Split(text, "|") {
+		idx := indexFold(names, part)
+		if idx >= 0 {
+			flags |= 1 << idx
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("unknown flag %q", part)
+		}
+		flags |= n
+	}
+	return flags, nil
+}
+
+func indexFold(names []string, name string) int {
+	for idx, n := range names {
+		if strings.EqualFold(n, name) {
+			return idx
+		}
+	}
+	return -1
+}
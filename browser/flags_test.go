@@ -0,0 +1,91 @@
+package browser_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func TestFlagsString(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "", browser.ServerFlags(0).String())
+	require.Equal(t, "password", browser.ServerFlagPassword.String())
+	require.Equal(t, "password|timescore|8", browser.ServerFlags(11).String())
+	require.True(t, browser.ServerFlags(3).Password())
+
+	require.Equal(t, "casual", browser.SkillLevelCasual.String())
+	require.Equal(t, "competitive", browser.SkillLevelCompetitive.String())
+	require.Equal(t, "7", browser.SkillLevel(7).String())
+
+	require.Equal(t, "player", browser.PlayerTypePlayer.String())
+	require.Equal(t, "spectator|bot", (browser.PlayerTypeSpectator | browser.PlayerTypeBot).String())
+	require.True(t, browser.PlayerType(3).Bot())
+	require.False(t, browser.PlayerTypeBot.Spectator())
+}
+
+func TestCountry(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "DE", browser.Country(276).String())
+	require.Equal(t, "GB-SCT", browser.Country(903).String())
+	require.Equal(t, "SS", browser.Country(737).String())
+	// the official id of South Sudan is not used by Teeworlds
+	require.False(t, browser.Country(728).Known())
+	require.Equal(t, "", browser.CountryNone.String())
+	require.Equal(t, "999", browser.Country(999).String())
+	require.False(t, browser.Country(999).Known())
+
+	for code, expected := range map[string]browser.Country{
+		"de":     276,
+		"US":     840,
+		"SS":     737,
+		"gb-eng": 901,
+		"":       browser.CountryNone,
+		"999":    999,
+	} {
+		country, err := browser.ParseCountry(code)
+		require.NoError(t, err, code)
+		require.Equal(t, expected, country, code)
+	}
+
+	_, err := browser.ParseCountry("Germany")
+	require.ErrorIs(t, browser.ErrUnknownValue, err)
+}
+
+func TestFlagsJSON(t *testing.T) {
+	t.Parallel()
+
+	info := serverInfo("json")
+	info.ServerFlags = browser.ServerFlagPassword | browser.ServerFlagTimeScore
+	info.Players[0].Country = 40
+	info.Players[1].Type = browser.PlayerTypeSpectator | browser.PlayerTypeBot
+
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+
+	var raw struct {
+		ServerFlags string `json:"server_flags"`
+		SkillLevel  string `json:"skill_level"`
+		Players     []struct {
+			Type    string `json:"type"`
+			Country string `json:"country"`
+		} `json:"players"`
+	}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	require.Equal(t, "password|timescore", raw.ServerFlags)
+	require.Equal(t, "competitive", raw.SkillLevel)
+	require.Equal(t, "AT", raw.Players[0].Country)
+	require.Equal(t, "player", raw.Players[0].Type)
+	require.Equal(t, "spectator|bot", raw.Players[1].Type)
+
+	var decoded browser.ServerInfo
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.True(t, info.Equal(decoded))
+
+	var flags browser.ServerFlags
+	err = json.Unmarshal([]byte(`"password|unknown"`), &flags)
+	require.ErrorIs(t, browser.ErrUnknownValue, err)
+}
@@ -0,0 +1,106 @@
+package browser
+
+import (
+	"context"
+	"net/netip"
+	"time"
+)
+
+// MasterHealth is the health report of a single master server.
+// A healthy master server is reachable and sends as many addresses as it advertises.
+type MasterHealth struct {
+	Address string `json:"address"`
+	// Reachable is set if the master server responded to the server count request
+	Reachable bool `json:"reachable"`
+	// TokenRTT is the round trip time of the token request, zero for Protocol06 and ProtocolDDNet
+	TokenRTT time.Duration `json:"token_rtt"`
+	// Advertised is the number of servers that the master server reported with the server count
+	Advertised int `json:"advertised"`
+	// Received is the number of addresses that were actually received
+	Received int `json:"received"`
+	// Unique is the number of unique addresses that were received
+	Unique int `json:"unique"`
+	// ChunksExpected is the number of server list packets that are needed for all advertised servers
+	ChunksExpected int `json:"chunks_expected"`
+	// ChunksReceived is the number of server list packets that were received
+	ChunksReceived int `json:"chunks_received"`
+	// Err is the error that aborted the requests
+	Err error `json:"-"`
+}
+
+// ChunksLost returns the number of server list packets that were not received
+func (h *MasterHealth) ChunksLost() int {
+	return max(0, h.ChunksExpected-h.ChunksReceived)
+}
+
+// Degraded returns true if the master server did not respond or did not send all advertised servers
+func (h *MasterHealth) Degraded() bool {
+	return !h.Reachable || h.Err != nil || h.Received < h.Advertised
+}
+
+// GetMasterHealth requests the server list of the master server and reports its health
+func (c *Client) GetMasterHealth() MasterHealth {
+	return c.GetMasterHealthContext(context.Background())
+}
+
+// GetMasterHealthContext is like GetMasterHealth but aborts as soon as the context is done.
+func (c *Client) GetMasterHealthContext(ctx context.Context) MasterHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, h := c.getMasterHealth(ctx)
+	return h
+}
+
+// getMasterHealth returns the unique addresses that were received together with the health report
+func (c *Client) getMasterHealth(ctx context.Context) ([]netip.AddrPort, MasterHealth) {
+	h := MasterHealth{
+		Address: c.target.String(),
+	}
+	if !c.protocol.legacy() {
+		// the token is requested even if a cached one exists in order to measure the round trip time
+		start := time.Now()
+		_, err := c.requestToken(ctx)
+		if err != nil {
+			h.Err = err
+			return nil, h
+		}
+		h.TokenRTT = time.Since(start)
+	}
+
+	list, err := c.getServerAddresses(ctx, &h)
+	h.Err = err
+
+	set := make(map[netip.AddrPort]struct{}, len(list))
+	result := make([]netip.AddrPort, 0, len(list))
+	for _, addr := range list {
+		if _, ok := set[addr]; ok {
+			continue
+		}
+		set[addr] = struct{}{}
+		result = append(result, addr)
+	}
+	h.Unique = len(result)
+	return result, h
+}
+
+// GetMasterHealthContext requests the server lists of all master servers and reports their health.
+// With WithSources only the master servers of the MasterSources are checked.
+func GetMasterHealthContext(ctx context.Context, options ...ScannerOption) ([]MasterHealth, error) {
+	cfg := newScanner(options...)
+	sources, err := cfg.serverListSources()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MasterHealth, 0, len(MasterServerAddresses))
+	for _, source := range sources {
+		ms, ok := source.(*MasterSource)
+		if !ok {
+			continue
+		}
+		_, _ = ms.ServerList(ctx)
+		result = append(result, ms.Health()...)
+	}
+
+	return result, ctx.Err()
+}
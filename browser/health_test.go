@@ -0,0 +1,70 @@
+package browser_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func TestClientGetMasterHealth(t *testing.T) {
+	t.Parallel()
+	m := newMaster(t, serverAddresses(100)...)
+
+	c, err := browser.NewClient(m.Addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	h := c.GetMasterHealth()
+	require.NoError(t, h.Err)
+	require.Equal(t, m.Addr, h.Address)
+	require.True(t, h.Reachable)
+	require.Greater(t, time.Duration(0), h.TokenRTT)
+	require.Equal(t, 100, h.Advertised)
+	require.Equal(t, 100, h.Received)
+	require.Equal(t, 100, h.Unique)
+	require.Equal(t, 2, h.ChunksExpected)
+	require.Equal(t, 0, h.ChunksLost())
+	require.False(t, h.Degraded())
+}
+
+func TestGetMasterHealth(t *testing.T) {
+	t.Parallel()
+	healthy := newMaster(t, serverAddresses(10)...)
+	degraded := newMaster(t, serverAddresses(200)...)
+	degraded.SetFaults(browsertest.MasterFaults{DropChunks: []int{1}})
+	offline := newMaster(t)
+	require.NoError(t, offline.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	source := browser.NewMasterSource(browser.Protocol07, healthy.Addr, degraded.Addr, offline.Addr)
+	reports, err := browser.GetMasterHealthContext(ctx, browser.WithSources(source))
+	require.ErrorIs(t, context.DeadlineExceeded, err)
+	require.Len(t, 3, reports)
+
+	h := reports[0]
+	require.Equal(t, healthy.Addr, h.Address)
+	require.False(t, h.Degraded())
+	require.Equal(t, 10, h.Received)
+
+	h = reports[1]
+	require.Equal(t, degraded.Addr, h.Address)
+	require.True(t, h.Degraded())
+	require.True(t, h.Reachable)
+	require.Equal(t, 200, h.Advertised)
+	require.Equal(t, 3, h.ChunksExpected)
+	require.Equal(t, 2, h.ChunksReceived)
+	require.Equal(t, 1, h.ChunksLost())
+	require.Equal(t, 125, h.Received)
+	require.ErrorIs(t, context.DeadlineExceeded, h.Err)
+
+	h = reports[2]
+	require.Equal(t, offline.Addr, h.Address)
+	require.True(t, h.Degraded())
+	require.False(t, h.Reachable)
+	require.NotNil(t, h.Err)
+}
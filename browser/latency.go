@@ -0,0 +1,52 @@
+package browser
+
+import (
+	"time"
+)
+
+// Latency summarizes the round trip times of the server info requests to a game server.
+// The durations are marshaled as nanoseconds.
+type Latency struct {
+	// RTT is the round trip time of the first measurement
+	RTT    time.Duration `json:"rtt"`
+	Min    time.Duration `json:"min"`
+	Avg    time.Duration `json:"avg"`
+	Max    time.Duration `json:"max"`
+	Jitter time.Duration `json:"jitter"`
+	// Samples is the number of measurements
+	Samples int `json:"samples"`
+}
+
+// newLatency creates the summary of the measured round trip times.
+// The jitter is the mean difference between two consecutive measurements.
+func newLatency(samples []time.Duration) *Latency {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	l := &Latency{
+		RTT:     samples[0],
+		Min:     samples[0],
+		Max:     samples[0],
+		Samples: len(samples),
+	}
+
+	var sum, diffs time.Duration
+	for idx, rtt := range samples {
+		sum += rtt
+		l.Min = min(l.Min, rtt)
+		l.Max = max(l.Max, rtt)
+		if idx > 0 {
+			diff := rtt - samples[idx-1]
+			if diff < 0 {
+				diff = -diff
+			}
+			diffs += diff
+		}
+	}
+	l.Avg = sum / time.Duration(len(samples))
+	if len(samples) > 1 {
+		l.Jitter = diffs / time.Duration(len(samples)-1)
+	}
+	return l
+}
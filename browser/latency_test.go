@@ -0,0 +1,62 @@
+package browser_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func TestScannerLatency(t *testing.T) {
+	t.Parallel()
+	const delay = 20 * time.Millisecond
+	server := newServer(t, serverInfo("delayed"))
+	server.SetFaults(browsertest.Faults{Delay: delay})
+
+	s, err := browser.NewScanner(browser.WithLatencySamples(3))
+	require.NoError(t, err)
+	defer s.Close()
+
+	infos, err := s.Scan(server.Addr)
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+	// every sample is a separate server info request
+	require.Equal(t, 3, server.Requests())
+
+	latency := infos[0].Latency
+	require.NotNil(t, latency)
+	require.Equal(t, 3, latency.Samples)
+	require.GreaterOrEqual(t, delay, latency.Min)
+	require.GreaterOrEqual(t, latency.Min, latency.Avg)
+	require.GreaterOrEqual(t, latency.Avg, latency.Max)
+	require.LessOrEqual(t, latency.Max-latency.Min, latency.Jitter)
+
+	data, err := json.Marshal(infos[0])
+	require.NoError(t, err)
+	require.True(t, strings.Contains(string(data), `"latency":{"rtt":`), string(data))
+}
+
+func TestClientLatency(t *testing.T) {
+	t.Parallel()
+	server := newServer(t, serverInfo("direct"))
+
+	c, err := browser.NewClient(server.Addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	info, err := c.GetServerInfo()
+	require.NoError(t, err)
+	require.NotNil(t, info.Latency)
+	require.Equal(t, 1, info.Latency.Samples)
+	require.Greater(t, time.Duration(0), info.Latency.RTT)
+
+	// the latency is omitted if it was not measured
+	info.Latency = nil
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+	require.False(t, strings.Contains(string(data), "latency"))
+}
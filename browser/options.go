@@ -0,0 +1,125 @@
+package browser
+
+import "time"
+
+// ScannerOption configures a Scanner and the functions that fetch server lists or server infos
+type ScannerOption func(*Scanner)
+
+// WithScanTimeout sets the maximum duration of a single scan.
+// Servers that did not respond until then are considered to be offline.
+func WithScanTimeout(timeout time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.timeout = timeout
+	}
+}
+
+// WithRetries sets the number of additional attempts per server
+// before it is considered to be offline
+func WithRetries(retries int) ScannerOption {
+	return func(s *Scanner) {
+		s.retries = max(0, retries)
+	}
+}
+
+// WithRetryInterval sets the duration to wait for a response before a request is sent again
+func WithRetryInterval(interval time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.retryInterval = interval
+	}
+}
+
+// WithProtocol sets the protocol version that is used to communicate with
+// master servers and game servers. The default is Protocol07.
+func WithProtocol(p ProtocolVersion) ScannerOption {
+	return func(s *Scanner) {
+		s.protocol = p
+	}
+}
+
+// WithPacketInterval sets the minimum duration between two consecutive outgoing packets.
+// Zero disables the pacing.
+func WithPacketInterval(interval time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.packetInterval = max(0, interval)
+	}
+}
+
+// WithRateLimit limits the number of outgoing packets of all scans of the scanner to packetsPerSecond
+// with short bursts of up to burst packets. A rate of zero disables the limit, which is the default.
+// Unlike WithPacketInterval, an idle scanner saves up to burst packets that are sent right away.
+func WithRateLimit(packetsPerSecond float64, burst int) ScannerOption {
+	return func(s *Scanner) {
+		s.limiter = newTokenBucket(packetsPerSecond, burst)
+	}
+}
+
+// WithServerPacketInterval sets the minimum duration between two consecutive packets to the same game server,
+// e.g. between the token request and the server info request. Zero disables the pacing, which is the default.
+func WithServerPacketInterval(interval time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.serverInterval = max(0, interval)
+	}
+}
+
+// WithMaxInFlight limits the number of game servers that are scanned at the same time.
+// Further servers are only requested after a previous one responded or timed out.
+// The official client uses 25 concurrent requests. Zero disables the limit, which is the default.
+// The scan timeout is not extended, a low limit might require a longer scan timeout.
+func WithMaxInFlight(n int) ScannerOption {
+	return func(s *Scanner) {
+		s.maxInFlight = max(0, n)
+	}
+}
+
+// WithSources sets the sources that provide the list of game servers.
+// By default the UDP master servers of the selected protocol version are used.
+// Sources that already provide server infos, like the HTTPSource, are not scanned again.
+func WithSources(sources ...ServerListSource) ScannerOption {
+	return func(s *Scanner) {
+		s.sources = sources
+	}
+}
+
+// WithLatencySamples sets the number of server info requests per server that are used
+// in order to measure the latency. Every server info contains the min, avg and max
+// round trip time as well as the jitter of all samples. The default is a single sample.
+func WithLatencySamples(samples int) ScannerOption {
+	return func(s *Scanner) {
+		s.samples = max(1, samples)
+	}
+}
+
+// WithAddressFamily restricts the scan to IPv4 or IPv6 addresses. Addresses of the other family are
+// neither requested from the master servers nor scanned, which avoids timeouts on hosts with a broken
+// IPv6 setup. Scanning such an address results in ErrAddressFamily. The default is AddressFamilyAny.
+func WithAddressFamily(family AddressFamily) ScannerOption {
+	return func(s *Scanner) {
+		s.family = family
+	}
+}
+
+// WithLocalAddress binds the socket to a local ip or ip:port address.
+// The scan is restricted to the address family of the local address.
+func WithLocalAddress(address string) ScannerOption {
+	return func(s *Scanner) {
+		s.localAddress = address
+	}
+}
+
+// WithInterface binds the socket to an address of the network interface, e.g. "eth0".
+// IPv4 addresses are preferred unless WithAddressFamily(AddressFamilyIPv6) is set.
+// The scan is restricted to the address family of the chosen address.
+func WithInterface(name string) ScannerOption {
+	return func(s *Scanner) {
+		s.iface = name
+	}
+}
+
+// WithGroupedAddresses merges the server infos of game servers that are reachable via IPv4 and IPv6
+// into a single server info, see GroupServerInfos.
+// It does not affect streamed results, as they are sent before all server infos are known.
+func WithGroupedAddresses() ScannerOption {
+	return func(s *Scanner) {
+		s.group = true
+	}
+}
@@ -74,9 +74,10 @@ func parseServerCount(serverCountPayload []byte) (int, error) {
 		return 0, ErrInvalidResponseMessage
 	}
 
+	// big endian encoded
 	count := 0
-	for idx, b := range data {
-		count |= (int(b) << ((len(data) - 1) - idx))
+	for _, b := range data {
+		count = count<<8 | int(b)
 	}
 
 	return count, nil
@@ -9,11 +9,51 @@ import (
 
 // PlayerInfo contains a players externally visible information
 type PlayerInfo struct {
-	Name    string `json:"name"`
-	Clan    string `json:"clan"`
-	Type    int    `json:"type"`
-	Country int    `json:"country"`
-	Score   int    `json:"score"`
+	Name    string     `json:"name"`
+	Clan    string     `json:"clan"`
+	Type    PlayerType `json:"type"`
+	Country Country    `json:"country"`
+	Score   int        `json:"score"`
+	// Afk and Skin are only sent by DDNet servers with the extended server info
+	Afk  bool        `json:"afk,omitempty"`
+	Skin *PlayerSkin `json:"skin,omitempty"`
+}
+
+// PlayerSkin is the skin of a player that newer DDNet servers send with the extended server info.
+// The colors are only set if the player uses custom colors.
+type PlayerSkin struct {
+	Name      string `json:"name"`
+	ColorBody *int   `json:"color_body,omitempty"`
+	ColorFeet *int   `json:"color_feet,omitempty"`
+}
+
+// Equal compares two player infos, including the skins they point to
+func (p *PlayerInfo) Equal(other PlayerInfo) bool {
+	if p.Name != other.Name ||
+		p.Clan != other.Clan ||
+		p.Type != other.Type ||
+		p.Country != other.Country ||
+		p.Score != other.Score ||
+		p.Afk != other.Afk {
+		return false
+	}
+	return p.Skin.equal(other.Skin)
+}
+
+func (s *PlayerSkin) equal(other *PlayerSkin) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	return s.Name == other.Name &&
+		equalColor(s.ColorBody, other.ColorBody) &&
+		equalColor(s.ColorFeet, other.ColorFeet)
+}
+
+func equalColor(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 func (p *PlayerInfo) String() string {
@@ -30,9 +70,9 @@ func (p *PlayerInfo) MarshalBinary() ([]byte, error) {
 	packer.AddString(p.Name)
 	packer.AddString(p.Clan)
 
-	packer.AddInt(p.Country)
+	packer.AddInt(int(p.Country))
 	packer.AddInt(p.Score)
-	packer.AddInt(p.Type)
+	packer.AddInt(int(p.Type))
 
 	return packer.Bytes(), nil
 }
@@ -50,19 +90,22 @@ func (p *PlayerInfo) UnmarshalBinary(data []byte) error {
 		return fmt.Errorf("failed to unmarshal clan: %w", err)
 	}
 
-	p.Country, err = u.NextInt()
+	country, err := u.NextInt()
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal country code: %w", err)
 	}
+	p.Country = Country(country)
+
 	p.Score, err = u.NextInt()
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal score: %w", err)
 	}
-	p.Type, err = u.NextInt()
+	playerType, err := u.NextInt()
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal player type: %w", err)
 	}
-	return err
+	p.Type = PlayerType(playerType)
+	return nil
 }
 
 // PlayerInfos must be pre allocated in order for the unmarshaler to know the
@@ -83,18 +126,21 @@ func (pi PlayerInfos) UnmarshalBinary(data []byte) error {
 			return fmt.Errorf("failed to unmarshal clan: %w", err)
 		}
 
-		player.Country, err = u.NextInt()
+		country, err := u.NextInt()
 		if err != nil {
 			return err
 		}
+		player.Country = Country(country)
+
 		player.Score, err = u.NextInt()
 		if err != nil {
 			return err
 		}
-		player.Type, err = u.NextInt()
+		playerType, err := u.NextInt()
 		if err != nil {
 			return err
 		}
+		player.Type = PlayerType(playerType)
 
 		pi[idx] = player
 	}
@@ -0,0 +1,200 @@
+package browser
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Event is emitted by the Poller whenever a watched game server changes.
+// Use a type switch in order to handle the different events.
+type Event interface {
+	// ServerAddress returns the address of the game server that changed
+	ServerAddress() string
+}
+
+// ServerWentOnline is emitted when a server responds for the first time or again after it went offline
+type ServerWentOnline struct {
+	Address string
+	Info    ServerInfo
+}
+
+// ServerWentOffline is emitted when a server that responded before stops responding
+type ServerWentOffline struct {
+	Address string
+	// LastInfo is the last received server info
+	LastInfo ServerInfo
+	Err      error
+}
+
+// PlayerJoined is emitted when a player joins a server
+type PlayerJoined struct {
+	Address string
+	Player  PlayerInfo
+}
+
+// PlayerLeft is emitted when a player leaves a server
+type PlayerLeft struct {
+	Address string
+	Player  PlayerInfo
+}
+
+// ScoreChanged is emitted when the score of a player changes
+type ScoreChanged struct {
+	Address  string
+	Player   PlayerInfo
+	OldScore int
+}
+
+// NameChanged is emitted when the server name changes
+type NameChanged struct {
+	Address string
+	Old     string
+	New     string
+}
+
+// MapChanged is emitted when the map changes
+type MapChanged struct {
+	Address string
+	Old     string
+	New     string
+}
+
+// GameTypeChanged is emitted when the gametype changes
+type GameTypeChanged struct {
+	Address string
+	Old     string
+	New     string
+}
+
+// FlagsChanged is emitted when the server flags change
+type FlagsChanged struct {
+	Address string
+	Old     ServerFlags
+	New     ServerFlags
+}
+
+func (e *ServerWentOnline) ServerAddress() string  { return e.Address }
+func (e *ServerWentOffline) ServerAddress() string { return e.Address }
+func (e *PlayerJoined) ServerAddress() string      { return e.Address }
+func (e *PlayerLeft) ServerAddress() string        { return e.Address }
+func (e *ScoreChanged) ServerAddress() string      { return e.Address }
+func (e *NameChanged) ServerAddress() string       { return e.Address }
+func (e *MapChanged) ServerAddress() string        { return e.Address }
+func (e *GameTypeChanged) ServerAddress() string   { return e.Address }
+func (e *FlagsChanged) ServerAddress() string      { return e.Address }
+
+// NewPoller creates a poller that scans the passed game server addresses in the given interval.
+// The options configure the underlying Scanner.
+func NewPoller(interval time.Duration, addresses []string, options ...ScannerOption) (*Poller, error) {
+	s, err := NewScanner(options...)
+	if err != nil {
+		return nil, err
+	}
+	p := &Poller{
+		s:        s,
+		interval: interval,
+		known:    make(map[string]ServerInfo, len(addresses)),
+	}
+	p.SetAddresses(addresses...)
+	return p, nil
+}
+
+// Poller keeps watching a set of game servers and emits events for every change between two scans.
+type Poller struct {
+	s        *Scanner
+	interval time.Duration
+
+	mu        sync.Mutex
+	addresses []string
+	// known contains the last server info of every server that is online
+	known map[string]ServerInfo
+}
+
+// SetAddresses replaces the watched addresses. The change is applied with the next scan.
+func (p *Poller) SetAddresses(addresses ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addresses = append([]string(nil), addresses...)
+}
+
+// Close closes the underlying scanner
+func (p *Poller) Close() error {
+	return p.s.Close()
+}
+
+// Poll scans the watched servers immediately and after every interval and sends the events
+// to the returned channel. The first scan reports all responding servers with ServerWentOnline.
+// The channel is closed as soon as the context is done.
+// Poll must not be called again before the previous channel was closed.
+func (p *Poller) Poll(ctx context.Context) <-chan Event {
+	events := make(chan Event, 64)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			for _, e := range p.poll(ctx) {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return events
+}
+
+// poll executes a single scan and returns the events of all changes
+func (p *Poller) poll(ctx context.Context) []Event {
+	p.mu.Lock()
+	addresses := p.addresses
+	p.mu.Unlock()
+
+	var (
+		results = make(map[string]ScanResult, len(addresses))
+		watched = make(map[string]bool, len(addresses))
+		events  = make([]Event, 0, 8)
+	)
+	for result := range p.s.Stream(ctx, addresses...) {
+		results[result.Address] = result
+	}
+	if ctx.Err() != nil {
+		// incomplete scans would report all remaining servers as offline
+		return nil
+	}
+
+	for _, result := range results {
+		watched[result.Address] = true
+		last, online := p.known[result.Address]
+		switch {
+		case result.Err != nil && online:
+			delete(p.known, result.Address)
+			events = append(events, &ServerWentOffline{Address: result.Address, LastInfo: last, Err: result.Err})
+		case result.Err != nil:
+			// still offline
+		case !online:
+			p.known[result.Address] = result.Info
+			events = append(events, &ServerWentOnline{Address: result.Address, Info: result.Info})
+		default:
+			p.known[result.Address] = result.Info
+			diff := last.Diff(result.Info)
+			events = append(events, diff.Events()...)
+		}
+	}
+
+	// forget servers that are not watched anymore
+	for address := range p.known {
+		if !watched[address] {
+			delete(p.known, address)
+		}
+	}
+	return events
+}
@@ -0,0 +1,72 @@
+package browser_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// nextEvent returns the next event or fails the test after a timeout
+func nextEvent(t *testing.T, events <-chan browser.Event) browser.Event {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		require.True(t, ok, "event channel closed")
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout while waiting for an event")
+		return nil
+	}
+}
+
+func TestPoller(t *testing.T) {
+	t.Parallel()
+	info := serverInfo("watched")
+	s := newServer(t, info)
+
+	p, err := browser.NewPoller(50*time.Millisecond, []string{s.Addr},
+		browser.WithScanTimeout(time.Second),
+		browser.WithRetries(0),
+		browser.WithRetryInterval(100*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := p.Poll(ctx)
+
+	online, ok := nextEvent(t, events).(*browser.ServerWentOnline)
+	require.True(t, ok)
+	require.Equal(t, s.Addr, online.Address)
+	require.Equal(t, "watched", online.Info.Name)
+
+	changed := info
+	changed.Map = "ctf2"
+	changed.Players = append(browser.PlayerInfos{{Name: "late tee", Country: -1}}, info.Players...)
+	changed.NumClients = len(changed.Players)
+	s.SetInfo(changed)
+
+	mapChanged, ok := nextEvent(t, events).(*browser.MapChanged)
+	require.True(t, ok)
+	require.Equal(t, "ctf2", mapChanged.New)
+
+	joined, ok := nextEvent(t, events).(*browser.PlayerJoined)
+	require.True(t, ok)
+	require.Equal(t, "late tee", joined.Player.Name)
+
+	s.SetFaults(browsertest.Faults{Loss: 1})
+	offline, ok := nextEvent(t, events).(*browser.ServerWentOffline)
+	require.True(t, ok)
+	require.Equal(t, "ctf2", offline.LastInfo.Map)
+	require.ErrorIs(t, browser.ErrTimeout, offline.Err)
+
+	cancel()
+	for range events {
+		// drain until closed
+	}
+}
@@ -0,0 +1,242 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/jxsl13/twapi/compression"
+)
+
+// ProtocolVersion selects the connless protocol that is used in order to
+// communicate with master servers and game servers.
+type ProtocolVersion int
+
+const (
+	// Protocol07 is the tokenized connless protocol of Teeworlds 0.7
+	Protocol07 ProtocolVersion = iota
+	// Protocol06 is the connless protocol of Teeworlds 0.6 that does not use any tokens
+	Protocol06
+	// ProtocolDDNet is the 0.6 based protocol of DDNet that additionally supports
+	// extended server infos with more than 16 players that are split into multiple packets.
+	ProtocolDDNet
+)
+
+const (
+	// connlessHeader06 is the prefix of every 0.6 connless packet
+	connlessHeader06 = "\xff\xff\xff\xff\xff\xff"
+
+	// SendInfo06 is used by 0.6 game servers when sending the server info.
+	// It is followed by the request token as string.
+	SendInfo06 = "\xff\xff\xff\xffinf3"
+)
+
+func (p ProtocolVersion) String() string {
+	switch p {
+	case Protocol07:
+		return "0.7"
+	case Protocol06:
+		return "0.6"
+	case ProtocolDDNet:
+		return "ddnet"
+	default:
+		return fmt.Sprintf("unknown protocol version %d", int(p))
+	}
+}
+
+// legacy returns true for all protocols that use the 0.6 framing without any tokens
+func (p ProtocolVersion) legacy() bool {
+	return p == Protocol06 || p == ProtocolDDNet
+}
+
+// NewRequestPacket06 creates a new 0.6 request payload from a request header.
+// The 0.6 protocol does not need any token.
+func NewRequestPacket06(requestHeader string) []byte {
+	payload := make([]byte, 0, len(connlessHeader06)+len(requestHeader))
+	payload = append(payload, connlessHeader06...)
+	payload = append(payload, requestHeader...)
+	return payload
+}
+
+// UnmarshalBinary06 parses a 0.6 response that does not contain any token
+func (rp *ResponsePacket) UnmarshalBinary06(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(connlessHeader06)) {
+		return ErrInvalidHeaderFlags
+	}
+	rp.Token = Token{}
+	rp.matchHeader(data[len(connlessHeader06):])
+	return nil
+}
+
+// MarshalBinary06 returns the 0.6 binary representation of the ServerInfo
+// without the leading request token.
+// The 0.6 server info does neither contain a hostname nor a skill level.
+func (s *ServerInfo) MarshalBinary06() ([]byte, error) {
+	p := compression.NewPacker()
+	p.AddString(s.Version)
+	p.AddString(s.Name)
+	p.AddString(s.Map)
+	p.AddString(s.GameType)
+	p.AddString(strconv.Itoa(int(s.ServerFlags)))
+
+	p.AddString(strconv.Itoa(s.NumPlayers))
+	p.AddString(strconv.Itoa(s.MaxPlayers))
+	p.AddString(strconv.Itoa(len(s.Players)))
+	p.AddString(strconv.Itoa(s.MaxClients))
+
+	for _, player := range s.Players {
+		playerData, _ := player.MarshalBinary06()
+		p.AddBytes(playerData)
+	}
+	return p.Bytes(), nil
+}
+
+// UnmarshalBinary06 creates a server info from 0.6 binary data that does not
+// contain the leading request token anymore.
+func (s *ServerInfo) UnmarshalBinary06(data []byte) error {
+	u := compression.NewUnpacker(data)
+
+	var err error
+	s.Version, err = u.NextString()
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal version: %w", err)
+	}
+
+	s.Name, err = u.NextString()
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal name: %w", err)
+	}
+
+	s.Map, err = u.NextString()
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal map: %w", err)
+	}
+
+	s.GameType, err = u.NextString()
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal gametype: %w", err)
+	}
+
+	flags, err := nextIntString(u)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal server flags: %w", err)
+	}
+	s.ServerFlags = ServerFlags(flags)
+
+	s.NumPlayers, err = nextIntString(u)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal number of players: %w", err)
+	}
+	s.MaxPlayers, err = nextIntString(u)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal max players: %w", err)
+	}
+	s.NumClients, err = nextIntString(u)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal number of clients: %w", err)
+	}
+	s.MaxClients, err = nextIntString(u)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal max clients: %w", err)
+	}
+
+	if s.NumClients < 0 {
+		return fmt.Errorf("%w: negative number of clients", ErrMalformedResponseData)
+	}
+
+	// preallocation is needed for the unmarshaling to work
+	s.Players = make(PlayerInfos, s.NumClients)
+	return s.Players.UnmarshalBinary06(u.Bytes())
+}
+
+// MarshalBinary06 returns the 0.6 binary representation of the PlayerInfo.
+// 0.6 only differentiates between players and spectators.
+func (p *PlayerInfo) MarshalBinary06() ([]byte, error) {
+	packer := compression.NewPacker(make([]byte, 0, 2+len(p.Name)+len(p.Clan)+3*5))
+
+	packer.AddString(p.Name)
+	packer.AddString(p.Clan)
+
+	packer.AddString(strconv.Itoa(int(p.Country)))
+	packer.AddString(strconv.Itoa(p.Score))
+
+	isPlayer := "1"
+	if p.Type.Spectator() {
+		isPlayer = "0"
+	}
+	packer.AddString(isPlayer)
+
+	return packer.Bytes(), nil
+}
+
+// UnmarshalBinary06 parses the 0.6 player infos
+func (pi PlayerInfos) UnmarshalBinary06(data []byte) error {
+	u := compression.NewUnpacker(data)
+	var err error
+	for idx, player := range pi {
+
+		player.Name, err = u.NextString()
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal name: %w", err)
+		}
+		player.Clan, err = u.NextString()
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal clan: %w", err)
+		}
+
+		country, err := nextIntString(u)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal country code: %w", err)
+		}
+		player.Country = Country(country)
+		player.Score, err = nextIntString(u)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal score: %w", err)
+		}
+		isPlayer, err := nextIntString(u)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal player type: %w", err)
+		}
+
+		player.Type = 0
+		if isPlayer == 0 {
+			player.Type = PlayerTypeSpectator
+		}
+
+		pi[idx] = player
+	}
+	return err
+}
+
+// parseServerInfo06 parses the 0.6 server info response that still contains the request token
+func parseServerInfo06(serverInfoPayload []byte, address string) (ServerInfo, error) {
+	u := compression.NewUnpacker(serverInfoPayload)
+
+	info := ServerInfo{
+		Address: address,
+	}
+	// skip request token
+	_, err := u.NextString()
+	if err != nil {
+		return info, fmt.Errorf("failed to unmarshal request token: %w", err)
+	}
+
+	err = info.UnmarshalBinary06(u.Bytes())
+	if err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// nextIntString unpacks the next integer that is sent as decimal string
+func nextIntString(u *compression.Unpacker) (int, error) {
+	s, err := u.NextString()
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrMalformedResponseData, err)
+	}
+	return i, nil
+}
@@ -0,0 +1,86 @@
+package browser_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// serverInfo06 removes the fields from a server info that are not part of the 0.6 protocol
+func serverInfo06(info browser.ServerInfo) browser.ServerInfo {
+	info.Hostname = ""
+	info.SkillLevel = 0
+	return info
+}
+
+func TestServerInfoBinary06(t *testing.T) {
+	t.Parallel()
+	expected := serverInfo06(serverInfo("legacy"))
+
+	data, err := expected.MarshalBinary06()
+	require.NoError(t, err)
+
+	var info browser.ServerInfo
+	require.NoError(t, info.UnmarshalBinary06(data))
+	require.True(t, info.Equal(expected), "expected: %s, got: %s", expected.String(), info.String())
+
+	require.Error(t, info.UnmarshalBinary06(data[:len(data)/2]))
+}
+
+func TestClientProtocol06(t *testing.T) {
+	t.Parallel()
+	servers := serverAddresses(100)
+	m := newMaster(t, servers...)
+
+	c, err := browser.NewClient(m.Addr)
+	require.NoError(t, err)
+	defer c.Close()
+	c.SetProtocol(browser.Protocol06)
+
+	_, err = c.GetToken()
+	require.ErrorIs(t, browser.ErrUnsupportedProtocol, err)
+
+	count, err := c.GetServerCount()
+	require.NoError(t, err)
+	require.Equal(t, len(servers), count)
+
+	list, err := c.GetServerAddresses()
+	require.NoError(t, err)
+	require.Len(t, len(servers), list)
+}
+
+func TestClientGetServerInfo06(t *testing.T) {
+	t.Parallel()
+	expected := serverInfo06(serverInfo("legacy"))
+	s := newServer(t, expected)
+	expected.Address = s.Addr
+
+	c, err := browser.NewClient(s.Addr)
+	require.NoError(t, err)
+	defer c.Close()
+	c.SetProtocol(browser.Protocol06)
+
+	info, err := c.GetServerInfo()
+	require.NoError(t, err)
+	require.True(t, info.Equal(expected), "expected: %s, got: %s", expected.String(), info.String())
+}
+
+func TestGetServerInfosProtocol06(t *testing.T) {
+	expected := serverInfo06(serverInfo("legacy"))
+	s := newServer(t, expected)
+	expected.Address = s.Addr
+	m := newMaster(t, addrPort(t, s.Addr))
+
+	old := browser.MasterServerAddresses06
+	browser.MasterServerAddresses06 = []string{m.Addr}
+	defer func() {
+		browser.MasterServerAddresses06 = old
+	}()
+
+	infos, err := browser.GetServerInfosContext(context.Background(), browser.WithProtocol(browser.Protocol06))
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+	require.True(t, infos[0].Equal(expected), "expected: %s, got: %s", expected.String(), infos[0].String())
+}
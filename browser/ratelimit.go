@@ -0,0 +1,55 @@
+package browser
+
+import "time"
+
+// newTokenBucket creates a token bucket that is refilled with rate tokens per second
+// and holds up to burst tokens. A non positive rate disables the limit.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(max(1, burst)),
+		tokens: float64(max(1, burst)),
+	}
+}
+
+// tokenBucket limits the number of outgoing packets per second while allowing short bursts.
+// A nil bucket does not limit anything.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// interval returns the duration that is needed in order to refill a single token
+func (b *tokenBucket) interval() time.Duration {
+	return time.Duration(float64(time.Second) / b.rate)
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
+
+// ready returns true if a packet may be sent
+func (b *tokenBucket) ready(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.refill(now)
+	return b.tokens >= 1
+}
+
+// take consumes a token for a sent packet
+func (b *tokenBucket) take(now time.Time) {
+	if b == nil {
+		return
+	}
+	b.refill(now)
+	b.tokens--
+}
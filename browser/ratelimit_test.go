@@ -0,0 +1,76 @@
+package browser_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// scanDuration scans all servers and returns the duration of the scan
+func scanDuration(t *testing.T, servers []*browsertest.Server, options ...browser.ScannerOption) time.Duration {
+	t.Helper()
+	s, err := browser.NewScanner(options...)
+	require.NoError(t, err)
+	defer s.Close()
+
+	addresses := make([]string, 0, len(servers))
+	for _, server := range servers {
+		addresses = append(addresses, server.Addr)
+	}
+
+	start := time.Now()
+	infos, err := s.Scan(addresses...)
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+	require.Len(t, len(servers), infos)
+	return elapsed
+}
+
+func TestScannerRateLimit(t *testing.T) {
+	t.Parallel()
+	servers := make([]*browsertest.Server, 0, 5)
+	for i := 0; i < 5; i++ {
+		servers = append(servers, newServer(t, serverInfo("limited")))
+	}
+
+	// a token request and a server info request per server,
+	// the first two packets are sent right away
+	elapsed := scanDuration(t, servers, browser.WithRateLimit(50, 2))
+	require.GreaterOrEqual(t, 8*20*time.Millisecond, elapsed)
+}
+
+func TestScannerMaxInFlight(t *testing.T) {
+	t.Parallel()
+	const delay = 20 * time.Millisecond
+	servers := make([]*browsertest.Server, 0, 4)
+	for i := 0; i < 4; i++ {
+		server := newServer(t, serverInfo("delayed"))
+		server.SetFaults(browsertest.Faults{Delay: delay})
+		servers = append(servers, server)
+	}
+
+	// the servers are scanned one after another with two round trips each
+	elapsed := scanDuration(t, servers, browser.WithMaxInFlight(1))
+	require.GreaterOrEqual(t, 4*2*delay, elapsed)
+
+	for _, server := range servers {
+		require.Equal(t, 1, server.Requests())
+	}
+}
+
+func TestScannerServerPacketInterval(t *testing.T) {
+	t.Parallel()
+	const interval = 50 * time.Millisecond
+	servers := []*browsertest.Server{
+		newServer(t, serverInfo("paced 1")),
+		newServer(t, serverInfo("paced 2")),
+	}
+
+	// the servers are paced independently of each other
+	elapsed := scanDuration(t, servers, browser.WithServerPacketInterval(interval))
+	require.GreaterOrEqual(t, interval, elapsed)
+	require.Less(t, 3*interval, elapsed)
+}
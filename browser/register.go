@@ -0,0 +1,331 @@
+package browser
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	// Used for the master server registration
+	// Heartbeat is sent by game servers to the master servers in order to be listed.
+	// It is followed by the port of the game server as two byte big endian integer.
+	Heartbeat = "\xff\xff\xff\xffbea2"
+	// FirewallCheck is sent by the master server to the announced game server address after a heartbeat
+	FirewallCheck = "\xff\xff\xff\xfffw??"
+	// FirewallResponse is the game server's reply to the FirewallCheck
+	FirewallResponse = "\xff\xff\xff\xfffw!!"
+	// FirewallOK is sent by the master server after it received the FirewallResponse
+	FirewallOK = "\xff\xff\xff\xfffwok"
+	// FirewallError is sent by the master server if it could not reach the announced game server address
+	FirewallError = "\xff\xff\xff\xfffwer"
+)
+
+var (
+	// HeartbeatInterval is the default interval in which the Registrar sends heartbeats to the master servers
+	HeartbeatInterval = 15 * time.Second
+
+	// ErrFirewalled is reported by the Registrar if a master server could not reach the game server
+	ErrFirewalled = errors.New("game server is not reachable by the master server")
+)
+
+// NewRegistrar creates a registrar that announces the game server that listens on conn at the passed master servers.
+// Without any master addresses, the MasterServerAddresses are used or the MasterServerAddresses06 in case that
+// the protocol is changed with SetProtocol.
+// The registrar only writes to conn, the game server must pass all received packets to HandlePacket.
+func NewRegistrar(conn *net.UDPConn, masters ...string) (*Registrar, error) {
+	local, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil, ErrInvalidAddress
+	}
+
+	token, err := newClientToken()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Registrar{
+		conn:     conn,
+		token:    token,
+		port:     uint16(local.Port),
+		interval: HeartbeatInterval,
+		changed:  make(chan struct{}, 1),
+		masters:  make(map[netip.AddrPort]*registration, len(masters)),
+		explicit: len(masters) > 0,
+	}
+	err = r.setMasters(masters)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Registrar announces a game server at the master servers.
+// It requests a token from every master server and sends heartbeats with that token.
+// The master servers verify that the game server is reachable with a firewall check that
+// is answered by HandlePacket.
+type Registrar struct {
+	conn *net.UDPConn
+	// token is the registrar's own token that the master servers send back with every packet
+	token int
+	// changed notifies Run about a new heartbeat interval
+	changed chan struct{}
+
+	mu       sync.Mutex
+	port     uint16
+	protocol ProtocolVersion
+	interval time.Duration
+	masters  map[netip.AddrPort]*registration
+	explicit bool
+}
+
+// registration is the state of the game server at a single master server
+type registration struct {
+	address    string
+	addr       *net.UDPAddr
+	token      *Token
+	registered bool
+	err        error
+}
+
+// RegistrationStatus is the state of the registration at a single master server
+type RegistrationStatus struct {
+	Master     string
+	Registered bool
+	Err        error
+}
+
+func (r *Registrar) setMasters(masters []string) error {
+	if len(masters) == 0 {
+		masters = MasterServerAddresses
+		if r.protocol.legacy() {
+			masters = MasterServerAddresses06
+		}
+	}
+
+	list := make(map[netip.AddrPort]*registration, len(masters))
+	for _, address := range masters {
+		addr, err := resolveUDPAddr(address)
+		if err != nil {
+			return err
+		}
+		list[normalizeAddrPort(addr.AddrPort())] = &registration{
+			address: address,
+			addr:    addr,
+		}
+	}
+	r.masters = list
+	return nil
+}
+
+// SetProtocol sets the protocol version that is used to communicate with the master servers.
+// The default is Protocol07. In case that no master servers were passed to NewRegistrar,
+// the default master servers of the protocol version are used.
+func (r *Registrar) SetProtocol(p ProtocolVersion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.protocol = p
+	if r.explicit {
+		return nil
+	}
+	return r.setMasters(nil)
+}
+
+// SetPort sets the port that is announced to the master servers.
+// The default is the local port of the connection.
+func (r *Registrar) SetPort(port uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.port = port
+}
+
+// SetHeartbeatInterval sets the interval in which Run sends heartbeats
+func (r *Registrar) SetHeartbeatInterval(d time.Duration) {
+	r.mu.Lock()
+	r.interval = d
+	r.mu.Unlock()
+
+	select {
+	case r.changed <- struct{}{}:
+	default:
+		// Run is already notified
+	}
+}
+
+// Run sends heartbeats to all master servers until the context is done.
+// Changes of the heartbeat interval take effect immediately.
+// It always returns the context error.
+func (r *Registrar) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.heartbeatInterval())
+	defer ticker.Stop()
+	for {
+		err := r.Heartbeat()
+		if err != nil && Logging {
+			log.Printf("failed to send heartbeat: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-r.changed:
+			// the next heartbeat is sent at once, followed by heartbeats in the new interval
+			ticker.Reset(r.heartbeatInterval())
+		}
+	}
+}
+
+func (r *Registrar) heartbeatInterval() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.interval
+}
+
+// Heartbeat sends a heartbeat to every master server.
+// Master servers without a valid token receive a token request instead,
+// the heartbeat is sent as soon as the token response is passed to HandlePacket.
+func (r *Registrar) Heartbeat() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for _, m := range r.masters {
+		errs = append(errs, r.heartbeat(m))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registrar) heartbeat(m *registration) error {
+	var data []byte
+	if r.protocol.legacy() {
+		data = NewRequestPacket06(Heartbeat)
+	} else if m.token.Expired() {
+		m.token = nil
+		_, err := r.conn.WriteToUDP(newTokenRequestPacket(r.token), m.addr)
+		return err
+	} else {
+		var err error
+		data, err = NewRequestPacket(*m.token, Heartbeat)
+		if err != nil {
+			return err
+		}
+	}
+	data = binary.BigEndian.AppendUint16(data, r.port)
+	_, err := r.conn.WriteToUDP(data, m.addr)
+	return err
+}
+
+// Status returns the registration state of every master server
+func (r *Registrar) Status() []RegistrationStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]RegistrationStatus, 0, len(r.masters))
+	for _, m := range r.masters {
+		result = append(result, RegistrationStatus{
+			Master:     m.address,
+			Registered: m.registered,
+			Err:        m.err,
+		})
+	}
+	return result
+}
+
+// HandlePacket processes the token responses and firewall packets of the master servers.
+// It returns false for all packets that must be processed by the game server itself.
+func (r *Registrar) HandlePacket(addr *net.UDPAddr, data []byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.masters[normalizeAddrPort(addr.AddrPort())]
+
+	resp := ResponsePacket{}
+	if r.protocol.legacy() {
+		if resp.UnmarshalBinary06(data) != nil {
+			return false
+		}
+	} else if !isConnless(data) {
+		if m == nil || len(data) != tokenResponseSize {
+			return false
+		}
+		token := &Token{}
+		if token.UnmarshalBinary(data) != nil || token.ClientToken != r.token {
+			return false
+		}
+		m.token = token
+		err := r.heartbeat(m)
+		if err != nil && Logging {
+			log.Printf("failed to send heartbeat to %s: %v\n", m.address, err)
+		}
+		return true
+	} else if len(data) < tokenPrefixSize || resp.UnmarshalBinary(data) != nil {
+		return false
+	}
+
+	switch resp.ResponseHeader {
+	case FirewallCheck:
+		if !r.trustedCheck(addr, data) {
+			// never reflect packets to anyone else than the master servers
+			return true
+		}
+		err := r.firewallResponse(addr, data)
+		if err != nil && Logging {
+			log.Printf("failed to send firewall response to %s: %v\n", addr, err)
+		}
+		return true
+	case FirewallOK:
+		if m == nil {
+			return false
+		}
+		m.registered = true
+		m.err = nil
+		return true
+	case FirewallError:
+		if m == nil {
+			return false
+		}
+		m.registered = false
+		m.err = ErrFirewalled
+		return true
+	default:
+		return false
+	}
+}
+
+// trustedCheck returns true if the firewall check was sent by one of the master servers.
+// The check may be sent from another port than the master server's, thus its IP address
+// or the registrar's token in the check are sufficient.
+func (r *Registrar) trustedCheck(addr *net.UDPAddr, check []byte) bool {
+	ip := normalizeAddrPort(addr.AddrPort()).Addr()
+	for key := range r.masters {
+		if key.Addr() == ip {
+			return true
+		}
+	}
+	return !r.protocol.legacy() && int(binary.BigEndian.Uint32(check[1:5])) == r.token
+}
+
+// firewallResponse answers the firewall check with the tokens of the check swapped
+func (r *Registrar) firewallResponse(addr *net.UDPAddr, check []byte) error {
+	var data []byte
+	if r.protocol.legacy() {
+		data = NewRequestPacket06(FirewallResponse)
+	} else {
+		// the receiver token is ours, the sender token is the master server's
+		token := Token{
+			ServerToken: int(binary.BigEndian.Uint32(check[5:9])),
+			ClientToken: int(binary.BigEndian.Uint32(check[1:5])),
+		}
+		var err error
+		data, err = NewRequestPacket(token, FirewallResponse)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := r.conn.WriteToUDP(data, addr)
+	return err
+}
@@ -0,0 +1,183 @@
+package browser_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// newGameServerConn creates the socket of a game server that passes all
+// packets to the registrar until the end of the test
+func newGameServerConn(t *testing.T) (*net.UDPConn, func(*browser.Registrar)) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	serve := func(r *browser.Registrar) {
+		go func() {
+			buffer := make([]byte, 1500)
+			for {
+				n, addr, err := conn.ReadFromUDP(buffer)
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				if err == nil {
+					r.HandlePacket(addr, buffer[:n])
+				}
+			}
+		}()
+	}
+	return conn, serve
+}
+
+// waitRegistered waits until the registrar is registered at all master servers
+func waitRegistered(t *testing.T, r *browser.Registrar) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		registered := true
+		for _, status := range r.Status() {
+			registered = registered && status.Registered
+		}
+		if registered {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("not registered: %v", r.Status())
+}
+
+func testRegistrar(t *testing.T, protocol browser.ProtocolVersion) {
+	m1 := newMaster(t)
+	m2 := newMaster(t, serverAddresses(3)...)
+	conn, serve := newGameServerConn(t)
+	self := addrPort(t, conn.LocalAddr().String())
+
+	r, err := browser.NewRegistrar(conn, m1.Addr, m2.Addr)
+	require.NoError(t, err)
+	require.NoError(t, r.SetProtocol(protocol))
+	serve(r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = r.Run(ctx)
+	}()
+	waitRegistered(t, r)
+
+	require.True(t, slices.Contains(m1.Servers(), self))
+	require.True(t, slices.Contains(m2.Servers(), self))
+	require.Len(t, 4, m2.Servers())
+
+	// the registered server is part of the server list
+	c, err := browser.NewClient(m1.Addr)
+	require.NoError(t, err)
+	defer c.Close()
+	c.SetProtocol(protocol)
+
+	list, err := c.GetServerAddresses()
+	require.NoError(t, err)
+	require.Len(t, 1, list)
+	require.Equal(t, self, list[0])
+}
+
+func TestRegistrar(t *testing.T) {
+	t.Parallel()
+	testRegistrar(t, browser.Protocol07)
+}
+
+func TestRegistrarProtocol06(t *testing.T) {
+	t.Parallel()
+	testRegistrar(t, browser.Protocol06)
+}
+
+func TestRegistrarHandlePacket(t *testing.T) {
+	t.Parallel()
+	m := newMaster(t)
+	conn, _ := newGameServerConn(t)
+
+	r, err := browser.NewRegistrar(conn, m.Addr)
+	require.NoError(t, err)
+
+	addr, err := net.ResolveUDPAddr("udp", m.Addr)
+	require.NoError(t, err)
+
+	// packets of game clients are left to the game server
+	require.False(t, r.HandlePacket(addr, browser.NewTokenRequestPacket()))
+	info, err := browser.NewRequestPacket(browser.Token{}, browser.RequestInfo)
+	require.NoError(t, err)
+	require.False(t, r.HandlePacket(addr, info))
+
+	fwer, err := browser.NewRequestPacket(browser.Token{}, browser.FirewallError)
+	require.NoError(t, err)
+	require.True(t, r.HandlePacket(addr, fwer))
+
+	status := r.Status()
+	require.Len(t, 1, status)
+	require.False(t, status[0].Registered)
+	require.ErrorIs(t, browser.ErrFirewalled, status[0].Err)
+}
+
+func TestRegistrarFirewallCheckReflection(t *testing.T) {
+	t.Parallel()
+	conn, serve := newGameServerConn(t)
+	// the master server is not reachable, no token is ever received
+	r, err := browser.NewRegistrar(conn, "127.0.0.2:8283")
+	require.NoError(t, err)
+	serve(r)
+
+	victim, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer victim.Close()
+
+	// a forged firewall check without the registrar's token is not answered
+	check, err := browser.NewRequestPacket(browser.Token{}, browser.FirewallCheck)
+	require.NoError(t, err)
+	_, err = victim.WriteToUDP(check, conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+
+	require.NoError(t, victim.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+	_, _, err = victim.ReadFromUDP(make([]byte, 1500))
+	require.ErrorIs(t, os.ErrDeadlineExceeded, err)
+}
+
+func TestRegistrarHeartbeatInterval(t *testing.T) {
+	t.Parallel()
+	master, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer master.Close()
+	conn, _ := newGameServerConn(t)
+
+	r, err := browser.NewRegistrar(conn, master.LocalAddr().String())
+	require.NoError(t, err)
+	r.SetHeartbeatInterval(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = r.Run(ctx)
+	}()
+
+	// without any token response every heartbeat is a token request
+	buffer := make([]byte, 1500)
+	require.NoError(t, master.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, _, err = master.ReadFromUDP(buffer)
+	require.NoError(t, err)
+
+	// the new interval is used by the running registrar
+	r.SetHeartbeatInterval(10 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		_, _, err = master.ReadFromUDP(buffer)
+		require.NoError(t, err)
+	}
+}
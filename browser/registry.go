@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// ResponseHandler handles a response whose header was registered with RegisterResponseHeader.
+// The payload is only valid for the duration of the call and must be copied in order to keep it.
+type ResponseHandler func(resp *ResponsePacket) error
+
+var customHeaders = headerRegistry{
+	handlers: make(map[string]ResponseHandler),
+}
+
+// RegisterResponseHeader registers a custom connless response header, e.g. of a mod, together with
+// the handler that is called for every response with that header that a Client or Scanner receives.
+// The built in headers of the ResponsePacketList are always matched first and cannot be registered.
+// In case multiple custom headers match, the longest one wins.
+func RegisterResponseHeader(header string, handler ResponseHandler) error {
+	if header == "" || handler == nil {
+		return fmt.Errorf("%w: header and handler must not be empty", ErrInvalidResponseHeader)
+	}
+	for _, prefix := range ResponsePacketList {
+		if string(prefix) == header {
+			return fmt.Errorf("%w: %q", ErrResponseHeaderRegistered, header)
+		}
+	}
+	return customHeaders.register(header, handler)
+}
+
+// UnregisterResponseHeader removes a custom response header and its handler.
+// Responses with that header are passed through with an empty header again.
+func UnregisterResponseHeader(header string) {
+	customHeaders.unregister(header)
+}
+
+// headerRegistry contains the custom response headers
+type headerRegistry struct {
+	mu       sync.RWMutex
+	headers  [][]byte
+	handlers map[string]ResponseHandler
+}
+
+func (r *headerRegistry) register(header string, handler ResponseHandler) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.handlers[header]; ok {
+		return fmt.Errorf("%w: %q", ErrResponseHeaderRegistered, header)
+	}
+	r.handlers[header] = handler
+	r.headers = append(r.headers, []byte(header))
+	return nil
+}
+
+func (r *headerRegistry) unregister(header string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.handlers[header]; !ok {
+		return
+	}
+	delete(r.handlers, header)
+	for idx, prefix := range r.headers {
+		if string(prefix) == header {
+			r.headers = append(r.headers[:idx], r.headers[idx+1:]...)
+			break
+		}
+	}
+}
+
+// match returns the longest registered header that prefixes the data
+func (r *headerRegistry) match(data []byte) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var longest []byte
+	for _, prefix := range r.headers {
+		if len(prefix) > len(longest) && bytes.HasPrefix(data, prefix) {
+			longest = prefix
+		}
+	}
+	return string(longest), longest != nil
+}
+
+func (r *headerRegistry) handler(header string) (ResponseHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	handler, ok := r.handlers[header]
+	return handler, ok
+}
+
+// dispatch passes the response to the handler of its custom header.
+// It returns false for all responses without a custom header.
+func dispatch(resp *ResponsePacket) (bool, error) {
+	handler, ok := customHeaders.handler(resp.ResponseHeader)
+	if !ok {
+		return false, nil
+	}
+	return true, handler(resp)
+}
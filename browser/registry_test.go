@@ -0,0 +1,65 @@
+package browser_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// registerHeader registers a custom response header for the duration of the test
+func registerHeader(t *testing.T, header string, handler browser.ResponseHandler) {
+	t.Helper()
+	require.NoError(t, browser.RegisterResponseHeader(header, handler))
+	t.Cleanup(func() {
+		browser.UnregisterResponseHeader(header)
+	})
+}
+
+func TestRegisterResponseHeader(t *testing.T) {
+	t.Parallel()
+	handler := func(*browser.ResponsePacket) error { return nil }
+
+	require.ErrorIs(t, browser.ErrInvalidResponseHeader, browser.RegisterResponseHeader("", handler))
+	require.ErrorIs(t, browser.ErrInvalidResponseHeader, browser.RegisterResponseHeader("\xff\xff\xff\xffreg1", nil))
+	require.ErrorIs(t, browser.ErrResponseHeaderRegistered, browser.RegisterResponseHeader(browser.SendInfo, handler))
+
+	registerHeader(t, "\xff\xff\xff\xffreg1", handler)
+	require.ErrorIs(t, browser.ErrResponseHeaderRegistered, browser.RegisterResponseHeader("\xff\xff\xff\xffreg1", handler))
+}
+
+func TestClientRequestCustomHeader(t *testing.T) {
+	t.Parallel()
+	const (
+		request  = "\xff\xff\xff\xffgmod"
+		response = "\xff\xff\xff\xffimod"
+	)
+	s := newServer(t, serverInfo("mod"))
+	s.Handle(request, func(payload []byte) (string, []byte) {
+		return response, []byte("mod info")
+	})
+
+	c, err := browser.NewClient(s.Addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	// unknown headers are passed through with an empty header
+	resp, err := c.Request(request)
+	require.NoError(t, err)
+	require.Equal(t, "", resp.ResponseHeader)
+	require.Equal(t, response+"mod info", string(resp.Payload))
+
+	errHandler := errors.New("handler error")
+	received := make(chan string, 1)
+	registerHeader(t, response, func(resp *browser.ResponsePacket) error {
+		received <- string(resp.Payload)
+		return errHandler
+	})
+
+	resp, err = c.Request(request)
+	require.ErrorIs(t, errHandler, err)
+	require.Equal(t, response, resp.ResponseHeader)
+	require.Equal(t, "mod info", <-received)
+	require.Equal(t, s.Addr, resp.Addr.String())
+}
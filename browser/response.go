@@ -36,19 +36,29 @@ func (rp *ResponsePacket) UnmarshalBinary(data []byte) error {
 		return err
 	}
 	// skip token part
-	data = data[tokenPrefixSize:]
+	rp.matchHeader(data[tokenPrefixSize:])
+	return nil
+}
 
+// matchHeader sets the response header and the payload of the data that follows the token prefix
+func (rp *ResponsePacket) matchHeader(data []byte) {
 	// try matching all known prefix values
 	// in order to set the header value
-	// TODO: externalize this and allow to register custom prefixes
-	offset := 0
 	for _, prefix := range ResponsePacketList {
 		if bytes.HasPrefix(data, prefix) {
-			offset = len(prefix)
 			rp.ResponseHeader = string(prefix)
-			break
+			rp.Payload = data[len(prefix):]
+			return
 		}
 	}
-	rp.Payload = data[offset:]
-	return nil
+
+	// fall back to the headers that were registered with RegisterResponseHeader
+	header, ok := customHeaders.match(data)
+	if ok {
+		rp.ResponseHeader = header
+		rp.Payload = data[len(header):]
+		return
+	}
+	rp.ResponseHeader = ""
+	rp.Payload = data
 }
@@ -0,0 +1,553 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NewScanner creates a scanner that fetches the server infos of many game servers
+// over a single UDP socket.
+func NewScanner(options ...ScannerOption) (*Scanner, error) {
+	s := newScanner(options...)
+	family, laddr, err := s.localUDPAddr()
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(family, laddr)
+	if err != nil {
+		return nil, err
+	}
+	s.c = c
+	s.family = family
+	return s, nil
+}
+
+// newScanner applies the options to a scanner without a socket
+func newScanner(options ...ScannerOption) *Scanner {
+	s := &Scanner{
+		timeout:        TimeoutServers,
+		retries:        2,
+		retryInterval:  time.Second,
+		packetInterval: time.Millisecond,
+		samples:        1,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Scanner sends token and server info requests to many game servers over a single UDP socket
+// and routes the responses back to the requests by their source address.
+// Scans of the same scanner are executed one after another.
+type Scanner struct {
+	c *Client
+
+	timeout        time.Duration
+	retries        int
+	retryInterval  time.Duration
+	packetInterval time.Duration
+	// serverInterval is the minimum duration between two packets to the same server
+	serverInterval time.Duration
+	protocol       ProtocolVersion
+	samples        int
+	sources        []ServerListSource
+	limiter        *tokenBucket
+	maxInFlight    int
+	family         AddressFamily
+	localAddress   string
+	iface          string
+	group          bool
+
+	mu sync.Mutex
+}
+
+// serverListSources returns the configured sources or the UDP master servers of the protocol
+func (s *Scanner) serverListSources() ([]ServerListSource, error) {
+	if len(s.sources) > 0 {
+		return s.sources, nil
+	}
+	family, laddr, err := s.localUDPAddr()
+	if err != nil {
+		return nil, err
+	}
+	ms := NewMasterSource(s.protocol)
+	ms.family = family
+	if laddr != nil {
+		// the port is already used by the scanner
+		ms.laddr = &net.UDPAddr{IP: laddr.IP, Zone: laddr.Zone}
+	}
+	return []ServerListSource{ms}, nil
+}
+
+// fetchServerList fetches the server lists of all sources and removes the addresses
+// of the address family that is not used by the scanner.
+func (s *Scanner) fetchServerList(ctx context.Context) (ServerList, error) {
+	sources, err := s.serverListSources()
+	if err != nil {
+		return ServerList{}, err
+	}
+	family, _, err := s.localUDPAddr()
+	if err != nil {
+		return ServerList{}, err
+	}
+	list, err := fetchServerLists(ctx, sources)
+	return filterServerList(list, family), err
+}
+
+// Close closes the underlying UDP socket
+func (s *Scanner) Close() error {
+	return s.c.Close()
+}
+
+// Scan fetches the server infos of the passed game server addresses.
+// Servers that do not respond within the scan timeout are not part of the result.
+func (s *Scanner) Scan(addresses ...string) ([]ServerInfo, error) {
+	return s.ScanContext(context.Background(), addresses...)
+}
+
+// ScanContext is like Scan but aborts the scan as soon as the context is done.
+// The server infos that were received up to that point are returned together with the context error.
+func (s *Scanner) ScanContext(ctx context.Context, addresses ...string) ([]ServerInfo, error) {
+	targets := make([]*scanTarget, 0, len(addresses))
+	for _, address := range addresses {
+		t, err := s.newScanTarget(address)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	result := make([]ServerInfo, 0, len(targets))
+	err := s.scan(ctx, targets, func(_ string, info ServerInfo, err error) {
+		if err == nil {
+			result = append(result, info)
+		}
+	})
+	return result, err
+}
+
+// ScanResult is either the server info or the error that occurred while fetching
+// the server info of a single game server.
+type ScanResult struct {
+	Address string
+	Info    ServerInfo
+	Err     error
+}
+
+// Stream starts a scan in the background and sends the result of every address to the returned
+// channel as soon as it is available. Addresses that cannot be resolved or servers that do not respond
+// are sent with an error. The channel is closed after the scan finished.
+// The channel is buffered, thus the scan is never blocked by the receiver.
+func (s *Scanner) Stream(ctx context.Context, addresses ...string) <-chan ScanResult {
+	// every address produces at most one result
+	results := make(chan ScanResult, len(addresses))
+	go func() {
+		defer close(results)
+		s.stream(ctx, addresses, results)
+	}()
+	return results
+}
+
+func (s *Scanner) stream(ctx context.Context, addresses []string, results chan<- ScanResult) {
+	targets := make([]*scanTarget, 0, len(addresses))
+	for _, address := range addresses {
+		t, err := s.newScanTarget(address)
+		if err != nil {
+			results <- ScanResult{Address: address, Err: err}
+			continue
+		}
+		targets = append(targets, t)
+	}
+
+	_ = s.scan(ctx, targets, func(address string, info ServerInfo, err error) {
+		results <- ScanResult{Address: address, Info: info, Err: err}
+	})
+}
+
+// newScanTarget resolves the address, addresses of another address family result in ErrAddressFamily
+func (s *Scanner) newScanTarget(address string) (*scanTarget, error) {
+	addr, err := s.c.family.resolveUDPAddr(address)
+	if err != nil {
+		return nil, err
+	}
+	return &scanTarget{
+		key:      normalizeAddrPort(addr.AddrPort()),
+		addr:     addr,
+		attempts: 1,
+		queued:   true,
+	}, nil
+}
+
+// scanTarget is the state of a single game server during a scan
+type scanTarget struct {
+	key      netip.AddrPort
+	addr     *net.UDPAddr
+	token    *Token
+	attempts int
+	sentAt   time.Time
+	// nextSend is the earliest time of the next request to this server
+	nextSend time.Time
+	queued   bool
+	// started is set as soon as the first request was sent
+	started bool
+	// info is the first received server info while further latency samples are collected
+	info *ServerInfo
+	rtts []time.Duration
+	// parts of the DDNet extended server info that were received so far
+	parts *MultiPartServerInfo
+}
+
+// scanPacket is a packet that was received by the scanner
+type scanPacket struct {
+	addr       netip.AddrPort
+	data       []byte
+	receivedAt time.Time
+}
+
+// scan calls report exactly once for every unique target address, either with the
+// server info or with the error that occurred while fetching it.
+func (s *Scanner) scan(ctx context.Context, list []*scanTarget, report func(address string, info ServerInfo, err error)) error {
+	var (
+		targets = make(map[netip.AddrPort]*scanTarget, len(list))
+		// pending targets were not sent any request yet
+		pending = make([]*scanTarget, 0, len(list))
+		// queue contains the running targets that need to send their next request
+		queue   = make([]*scanTarget, 0, len(list))
+		running = 0
+	)
+	for _, t := range list {
+		if _, ok := targets[t.key]; ok {
+			continue
+		}
+		if token := s.c.tokenCache.Get(t.key.String()); !token.Expired() {
+			t.token = token
+		}
+		targets[t.key] = t
+		pending = append(pending, t)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scanCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	packets := make(chan scanPacket, 64)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.receive(scanCtx, packets)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	var (
+		tick          = s.packetInterval
+		checkInterval = max(10*time.Millisecond, s.retryInterval/4)
+		lastCheck     = time.Now()
+		nextSend      time.Time
+	)
+	if tick <= 0 {
+		tick = checkInterval
+	}
+	if s.limiter != nil {
+		tick = min(tick, max(time.Millisecond, s.limiter.interval()))
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	finish := func(t *scanTarget, info ServerInfo, err error) {
+		delete(targets, t.key)
+		if t.started {
+			running--
+		}
+		report(t.key.String(), info, err)
+	}
+	// next returns the next target that may send a request or nil.
+	// Running targets are preferred over pending ones.
+	next := func(now time.Time) *scanTarget {
+		for idx, t := range queue {
+			if now.Before(t.nextSend) {
+				// per server pacing
+				continue
+			}
+			if idx == 0 {
+				queue = queue[1:]
+			} else {
+				queue = slices.Delete(queue, idx, idx+1)
+			}
+			return t
+		}
+		if len(pending) == 0 || (s.maxInFlight > 0 && running >= s.maxInFlight) {
+			return nil
+		}
+		t := pending[0]
+		pending = pending[1:]
+		t.started = true
+		running++
+		return t
+	}
+	enqueue := func(t *scanTarget) {
+		if !t.queued {
+			t.queued = true
+			queue = append(queue, t)
+		}
+	}
+	// fail reports the error unless the server info was already received.
+	// Incomplete DDNet extended server infos are reported with the players that were received so far.
+	fail := func(t *scanTarget, err error) {
+		if t.info == nil && t.parts.hasFirstPart() {
+			info := t.parts.ServerInfo()
+			info.Address = t.key.String()
+			t.info = &info
+		}
+		if t.info == nil {
+			finish(t, ServerInfo{}, err)
+			return
+		}
+		info := *t.info
+		info.Latency = newLatency(t.rtts)
+		finish(t, info, nil)
+	}
+	// retry requests the server info again after a response that could not be parsed,
+	// the error is reported once all attempts were used up
+	retry := func(t *scanTarget, err error) {
+		if t.attempts > s.retries {
+			fail(t, err)
+			return
+		}
+		t.attempts++
+		enqueue(t)
+	}
+	// complete measures the round trip time and requests the server info again
+	// until all latency samples were collected
+	complete := func(t *scanTarget, info ServerInfo, receivedAt time.Time) {
+		t.rtts = append(t.rtts, receivedAt.Sub(t.sentAt))
+		if len(t.rtts) < s.samples {
+			t.info = &info
+			t.parts = nil
+			enqueue(t)
+			return
+		}
+		info.Latency = newLatency(t.rtts)
+		finish(t, info, nil)
+	}
+
+	for len(targets) > 0 {
+		select {
+		case <-scanCtx.Done():
+			err := ctx.Err()
+			if err == nil {
+				err = ErrTimeout
+			}
+			for _, t := range targets {
+				fail(t, err)
+			}
+			return ctx.Err()
+		case p := <-packets:
+			if s.dispatch(p) {
+				continue
+			}
+			t, ok := targets[p.addr]
+			if !ok {
+				continue
+			}
+			if s.protocol == ProtocolDDNet {
+				resp := ResponsePacket{}
+				if resp.UnmarshalBinary06(p.data) != nil {
+					continue
+				}
+				if t.parts == nil {
+					t.parts = &MultiPartServerInfo{}
+				}
+				if t.parts.Add(&resp) != nil || !t.parts.Complete() {
+					continue
+				}
+				info := t.parts.ServerInfo()
+				info.Address = t.key.String()
+				complete(t, info, p.receivedAt)
+				continue
+			}
+			if s.protocol.legacy() {
+				resp := ResponsePacket{}
+				if resp.UnmarshalBinary06(p.data) != nil || resp.ResponseHeader != SendInfo06 {
+					continue
+				}
+				info, err := parseServerInfo06(resp.Payload, t.key.String())
+				if err != nil {
+					retry(t, err)
+					continue
+				}
+				complete(t, info, p.receivedAt)
+				continue
+			}
+			if !isConnless(p.data) {
+				if t.token != nil {
+					// not waiting for a token
+					continue
+				}
+				token := &Token{}
+				if token.UnmarshalBinary(p.data) != nil {
+					continue
+				}
+				t.token = token
+				s.c.tokenCache.Add(t.key.String(), token)
+				enqueue(t)
+				continue
+			}
+
+			resp := ResponsePacket{}
+			if resp.UnmarshalBinary(p.data) != nil || resp.ResponseHeader != SendInfo {
+				continue
+			}
+			info, err := parseServerInfo(resp.Payload, t.key.String())
+			if err != nil {
+				retry(t, err)
+				continue
+			}
+			complete(t, info, p.receivedAt)
+		case now := <-ticker.C:
+			if now.Sub(lastCheck) < checkInterval {
+				break
+			}
+			lastCheck = now
+			for _, t := range targets {
+				if t.queued || now.Sub(t.sentAt) < s.retryInterval {
+					continue
+				}
+				if t.attempts > s.retries {
+					fail(t, ErrTimeout)
+					continue
+				}
+				// start over with a new token, the server might have rotated its token seed
+				t.attempts++
+				t.token = nil
+				enqueue(t)
+			}
+		}
+
+		// send queued requests
+		for {
+			now := time.Now()
+			if now.Before(nextSend) || !s.limiter.ready(now) {
+				break
+			}
+			t := next(now)
+			if t == nil {
+				break
+			}
+			if _, ok := targets[t.key]; !ok {
+				// already finished
+				continue
+			}
+
+			s.limiter.take(now)
+			err := s.send(scanCtx, t, now)
+			if err != nil {
+				finish(t, ServerInfo{}, err)
+				continue
+			}
+			nextSend = now.Add(s.packetInterval)
+			t.nextSend = now.Add(s.serverInterval)
+		}
+	}
+	return nil
+}
+
+// send sends either a token request or a server info request depending on the target's token
+func (s *Scanner) send(ctx context.Context, t *scanTarget, now time.Time) (err error) {
+	t.queued = false
+	t.sentAt = now
+
+	var data []byte
+	if s.protocol == ProtocolDDNet {
+		if Logging {
+			log.Printf("fetching server info for address: %s\n", t.key)
+		}
+		data = NewRequestPacketDDNet(RequestInfo)
+	} else if s.protocol.legacy() {
+		if Logging {
+			log.Printf("fetching server info for address: %s\n", t.key)
+		}
+		data = NewRequestPacket06(RequestInfo)
+	} else if t.token.Expired() {
+		if Logging {
+			log.Printf("fetching server info for address: %s\n", t.key)
+		}
+		t.token = nil
+		data = NewTokenRequestPacket()
+	} else {
+		data, err = NewRequestPacket(*t.token, RequestInfo)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = s.c.writeToUDP(ctx, data, t.addr)
+	return err
+}
+
+// dispatch passes packets with a custom response header to their handler
+// and returns true if the packet was handled.
+func (s *Scanner) dispatch(p scanPacket) bool {
+	resp := ResponsePacket{
+		Addr: net.UDPAddrFromAddrPort(p.addr),
+	}
+	var err error
+	if s.protocol.legacy() {
+		err = resp.UnmarshalBinary06(p.data)
+	} else if isConnless(p.data) {
+		err = resp.UnmarshalBinary(p.data)
+	} else {
+		// token response
+		return false
+	}
+	if err != nil {
+		return false
+	}
+
+	handled, err := dispatch(&resp)
+	if err != nil && Logging {
+		log.Printf("failed to handle response %q from %s: %v\n", resp.ResponseHeader, p.addr, err)
+	}
+	return handled
+}
+
+// receive forwards all incoming packets until the context is done
+func (s *Scanner) receive(ctx context.Context, packets chan<- scanPacket) {
+	buffer := [maxBufferSize]byte{}
+	for {
+		n, addr, err := s.c.readFromUDP(ctx, buffer[:])
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		data := make([]byte, n)
+		copy(data, buffer[:n])
+
+		select {
+		case packets <- scanPacket{addr: normalizeAddrPort(addr.AddrPort()), data: data, receivedAt: time.Now()}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// isConnless returns true for all packets except for control packets like the token response
+func isConnless(data []byte) bool {
+	return len(data) > 0 && (data[0]>>2)&netPacketFlagConnless != 0
+}
+
+// normalizeAddrPort converts IPv4-mapped IPv6 addresses to IPv4 addresses
+func normalizeAddrPort(addr netip.AddrPort) netip.AddrPort {
+	return netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+}
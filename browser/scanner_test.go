@@ -0,0 +1,157 @@
+package browser_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func newScanner(t *testing.T, options ...browser.ScannerOption) *browser.Scanner {
+	t.Helper()
+	s, err := browser.NewScanner(options...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+	return s
+}
+
+func TestScannerScan(t *testing.T) {
+	t.Parallel()
+
+	servers := make(map[string]*browsertest.Server, 50)
+	addresses := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		s := newServer(t, serverInfo(fmt.Sprintf("server %d", i)))
+		servers[s.Addr] = s
+		addresses = append(addresses, s.Addr)
+	}
+
+	s := newScanner(t)
+	infos, err := s.Scan(addresses...)
+	require.NoError(t, err)
+	require.Len(t, len(addresses), infos)
+
+	for _, info := range infos {
+		server, ok := servers[info.Address]
+		require.True(t, ok, "unexpected server address: %s", info.Address)
+		require.Equal(t, 1, server.Requests())
+	}
+}
+
+func TestScannerRetries(t *testing.T) {
+	t.Parallel()
+	s := newServer(t, serverInfo("flaky"))
+	// drops the token responses of the first two attempts
+	s.SetFaults(browsertest.Faults{Drop: 2})
+
+	scanner := newScanner(t,
+		browser.WithRetries(2),
+		browser.WithRetryInterval(50*time.Millisecond),
+	)
+
+	infos, err := scanner.Scan(s.Addr)
+	require.NoError(t, err)
+	require.Len(t, 1, infos)
+	require.Equal(t, "flaky", infos[0].Name)
+}
+
+func TestScannerGivesUp(t *testing.T) {
+	t.Parallel()
+	healthy := newServer(t, serverInfo("healthy"))
+	broken := newServer(t, serverInfo("broken"))
+	broken.SetFaults(browsertest.Faults{WrongToken: true})
+
+	scanner := newScanner(t,
+		browser.WithRetries(1),
+		browser.WithRetryInterval(50*time.Millisecond),
+		browser.WithPacketInterval(0),
+	)
+
+	start := time.Now()
+	infos, err := scanner.Scan(healthy.Addr, broken.Addr)
+	diff := time.Since(start)
+	require.NoError(t, err)
+	require.Less(t, time.Second, diff)
+	require.Len(t, 1, infos)
+	require.Equal(t, healthy.Addr, infos[0].Address)
+}
+
+func TestScannerTimeout(t *testing.T) {
+	t.Parallel()
+	healthy := newServer(t, serverInfo("healthy"))
+	delayed := newServer(t, serverInfo("delayed"))
+	delayed.SetFaults(browsertest.Faults{Delay: time.Minute})
+
+	scanner := newScanner(t, browser.WithScanTimeout(200*time.Millisecond))
+
+	start := time.Now()
+	infos, err := scanner.ScanContext(context.Background(), healthy.Addr, delayed.Addr)
+	diff := time.Since(start)
+	require.NoError(t, err)
+	require.Less(t, time.Second, diff)
+	require.Len(t, 1, infos)
+	require.Equal(t, healthy.Addr, infos[0].Address)
+}
+
+func TestScannerStream(t *testing.T) {
+	t.Parallel()
+	healthy := newServer(t, serverInfo("healthy"))
+	delayed := newServer(t, serverInfo("delayed"))
+	delayed.SetFaults(browsertest.Faults{Delay: 300 * time.Millisecond})
+	broken := newServer(t, serverInfo("broken"))
+	broken.SetFaults(browsertest.Faults{WrongToken: true})
+	invalid := "invalid address"
+
+	scanner := newScanner(t,
+		browser.WithRetries(1),
+		browser.WithRetryInterval(time.Second),
+	)
+
+	start := time.Now()
+	results := make(map[string]browser.ScanResult, 4)
+	for result := range scanner.Stream(context.Background(), healthy.Addr, delayed.Addr, broken.Addr, invalid) {
+		if result.Address == healthy.Addr {
+			// streamed before the slower servers responded
+			require.Less(t, 300*time.Millisecond, time.Since(start))
+		}
+		results[result.Address] = result
+	}
+	require.Len(t, 4, results)
+
+	require.NoError(t, results[healthy.Addr].Err)
+	require.Equal(t, "healthy", results[healthy.Addr].Info.Name)
+	require.NoError(t, results[delayed.Addr].Err)
+	require.Equal(t, "delayed", results[delayed.Addr].Info.Name)
+	require.ErrorIs(t, browser.ErrTimeout, results[broken.Addr].Err)
+	require.Error(t, results[invalid].Err)
+}
+
+func TestStreamServerInfosCanceled(t *testing.T) {
+	healthy := newServer(t, serverInfo("healthy"))
+	delayed := newServer(t, serverInfo("delayed"))
+	delayed.SetFaults(browsertest.Faults{Delay: time.Minute})
+	useMasters(t, newMaster(t, addrPort(t, healthy.Addr), addrPort(t, delayed.Addr)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(map[string]browser.ScanResult, 2)
+	for result := range browser.StreamServerInfos(ctx) {
+		results[result.Address] = result
+		if result.Address == healthy.Addr {
+			cancel()
+		}
+	}
+
+	require.NoError(t, results[healthy.Addr].Err)
+	require.Equal(t, "healthy", results[healthy.Addr].Info.Name)
+	if result, ok := results[delayed.Addr]; ok {
+		require.ErrorIs(t, context.Canceled, result.Err)
+	}
+}
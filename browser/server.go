@@ -3,30 +3,37 @@ package browser
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/jxsl13/twapi/compression"
 )
 
 // ServerInfo contains the server's general information
 type ServerInfo struct {
-	Address     string      `json:"address"`
+	Address string `json:"address"`
+	// Addresses lists all addresses of a server that is reachable via more than one address,
+	// e.g. via IPv4 and IPv6, including the Address. See GroupServerInfos.
+	Addresses   []string    `json:"addresses,omitempty"`
 	Version     string      `json:"version"`
 	Name        string      `json:"name"`
 	Hostname    string      `json:"hostname,omitempty"`
 	Map         string      `json:"map"`
 	GameType    string      `json:"gametype"`
-	ServerFlags byte        `json:"server_flags"`
-	SkillLevel  byte        `json:"skill_level"`
+	ServerFlags ServerFlags `json:"server_flags"`
+	SkillLevel  SkillLevel  `json:"skill_level"`
 	NumPlayers  int         `json:"num_players"`
 	MaxPlayers  int         `json:"max_players"`
 	NumClients  int         `json:"num_clients"`
 	MaxClients  int         `json:"max_clients"`
 	Players     PlayerInfos `json:"players"`
+	// Latency is only set if the server info was requested directly from the game server
+	Latency *Latency `json:"latency,omitempty"`
 }
 
 // Empty returns true if the whole struct does not contain any data at all
 func (s *ServerInfo) Empty() bool {
 	return s.Address == "" &&
+		len(s.Addresses) == 0 &&
 		s.Version == "" &&
 		s.Name == "" &&
 		s.Hostname == "" &&
@@ -38,13 +45,16 @@ func (s *ServerInfo) Empty() bool {
 		s.MaxPlayers == 0 &&
 		s.NumClients == 0 &&
 		s.MaxClients == 0 &&
-		len(s.Players) == 0
+		len(s.Players) == 0 &&
+		s.Latency == nil
 }
 
-// Equal compares two instances of ServerInfo and returns true if they are equal
+// Equal compares two instances of ServerInfo and returns true if they are equal.
+// The latency is not compared, as it differs with every measurement.
 func (s *ServerInfo) Equal(other ServerInfo) bool {
 
 	equalData := s.Address == other.Address &&
+		slices.Equal(s.Addresses, other.Addresses) &&
 		s.Version == other.Version &&
 		s.Name == other.Name &&
 		s.Hostname == other.Hostname &&
@@ -62,7 +72,7 @@ func (s *ServerInfo) Equal(other ServerInfo) bool {
 		return false
 	}
 	for idx, p := range s.Players {
-		if p != other.Players[idx] {
+		if !p.Equal(other.Players[idx]) {
 			return false
 		}
 	}
@@ -84,8 +94,8 @@ func (s *ServerInfo) MarshalBinary() ([]byte, error) {
 	p.AddString(s.Hostname)
 	p.AddString(s.Map)
 	p.AddString(s.GameType)
-	p.AddByte(s.ServerFlags)
-	p.AddByte(s.SkillLevel)
+	p.AddByte(byte(s.ServerFlags))
+	p.AddByte(byte(s.SkillLevel))
 
 	p.AddInt(s.NumPlayers)
 	p.AddInt(s.MaxPlayers)
@@ -130,14 +140,17 @@ func (s *ServerInfo) UnmarshalBinary(data []byte) error {
 		return fmt.Errorf("failed to unmarshal gametype: %w", err)
 	}
 
-	s.ServerFlags, err = u.NextByte()
+	flags, err := u.NextByte()
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal server flags: %w", err)
 	}
-	s.SkillLevel, err = u.NextByte()
+	s.ServerFlags = ServerFlags(flags)
+
+	level, err := u.NextByte()
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal skill levels: %w", err)
 	}
+	s.SkillLevel = SkillLevel(level)
 
 	s.NumPlayers, err = u.NextInt()
 	if err != nil {
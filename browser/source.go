@@ -0,0 +1,375 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+const (
+	// DefaultHTTPMasterURL is the server list that is published by the DDNet HTTP master servers
+	DefaultHTTPMasterURL = "https://master1.ddnet.org/ddnet/15/servers.json"
+)
+
+// ServerListSource provides the list of registered game servers.
+type ServerListSource interface {
+	// ServerList fetches the currently registered game servers.
+	// In case of an error the partial list that was received up to that point is returned.
+	ServerList(ctx context.Context) (ServerList, error)
+}
+
+// ServerList is the result of a ServerListSource.
+type ServerList struct {
+	// Addresses contains the game servers whose server infos still need to be requested
+	Addresses []netip.AddrPort
+	// Infos contains the server infos that were already provided by the source
+	Infos []ServerInfo
+}
+
+// NewMasterSource creates a source that requests the server lists from the passed UDP master servers.
+// Without any addresses, the MasterServerAddresses or MasterServerAddresses06 are used
+// depending on the protocol version.
+func NewMasterSource(protocol ProtocolVersion, addresses ...string) *MasterSource {
+	return &MasterSource{
+		addresses: addresses,
+		protocol:  protocol,
+	}
+}
+
+// MasterSource fetches the server addresses from UDP master servers
+type MasterSource struct {
+	addresses []string
+	protocol  ProtocolVersion
+	family    AddressFamily
+	laddr     *net.UDPAddr
+
+	mu     sync.Mutex
+	health []MasterHealth
+}
+
+// Health returns the health reports of all master servers of the last ServerList call
+func (ms *MasterSource) Health() []MasterHealth {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return append([]MasterHealth(nil), ms.health...)
+}
+
+// SetAddressFamily restricts the requests to master servers of the address family.
+// The received server lists are not filtered, as they are filtered by the Scanner.
+func (ms *MasterSource) SetAddressFamily(family AddressFamily) {
+	ms.family = family
+}
+
+// SetLocalAddr binds the sockets of the requests to the local address.
+// The address family must match the address family of the local address.
+func (ms *MasterSource) SetLocalAddr(laddr *net.UDPAddr) {
+	ms.laddr = laddr
+}
+
+// ServerList fetches the server lists of all master servers and returns the merged list of unique
+// game server addresses. Master servers that fail to respond are skipped, an error is only
+// returned if none of them responded. Use Health in order to find out which master servers failed.
+func (ms *MasterSource) ServerList(ctx context.Context) (ServerList, error) {
+	masters := ms.addresses
+	if len(masters) == 0 {
+		masters = MasterServerAddresses
+		if ms.protocol.legacy() {
+			masters = MasterServerAddresses06
+		}
+	}
+
+	var (
+		health  = make([]MasterHealth, len(masters))
+		clients = make([]*Client, len(masters))
+	)
+	for idx, addr := range masters {
+		client, err := newClientTo(addr, ms.family, ms.laddr)
+		if err != nil {
+			health[idx] = MasterHealth{Address: addr, Err: err}
+			continue
+		}
+		// called at the end of the function, not at the end of the loop
+		defer client.Close()
+		client.SetProtocol(ms.protocol)
+		clients[idx] = client
+	}
+
+	var (
+		set = make(map[netip.AddrPort]struct{}, 1024)
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+	)
+
+	for idx, client := range clients {
+		if client == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(idx int, c *Client) {
+			defer wg.Done()
+			// get all addresses from all master servers
+			// partial lists are kept in case of an error
+			c.mu.Lock()
+			list, h := c.getMasterHealth(ctx)
+			c.mu.Unlock()
+
+			mu.Lock()
+			defer mu.Unlock()
+			health[idx] = h
+			// add all addresses to a global set
+			for _, addr := range list {
+				set[addr] = struct{}{}
+			}
+		}(idx, client)
+	}
+	wg.Wait()
+
+	ms.mu.Lock()
+	ms.health = health
+	ms.mu.Unlock()
+
+	var (
+		firstErr  error
+		reachable = false
+	)
+	for _, h := range health {
+		if h.Degraded() && Logging {
+			log.Printf("master server %s is degraded: received %d of %d servers, %d chunks lost: %v\n",
+				h.Address, h.Received, h.Advertised, h.ChunksLost(), h.Err)
+		}
+		reachable = reachable || h.Reachable
+		if firstErr == nil {
+			firstErr = h.Err
+		}
+	}
+
+	result := make([]netip.AddrPort, 0, len(set))
+	for addr := range set {
+		result = append(result, addr)
+	}
+	if ctx.Err() != nil {
+		return ServerList{Addresses: result}, ctx.Err()
+	}
+	if !reachable && firstErr != nil {
+		return ServerList{}, fmt.Errorf("no master server responded: %w", firstErr)
+	}
+	return ServerList{Addresses: result}, nil
+}
+
+// NewHTTPSource creates a source that fetches the JSON server list of a HTTP master server,
+// e.g. DefaultHTTPMasterURL.
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{
+		url:    url,
+		client: http.DefaultClient,
+	}
+}
+
+// HTTPSource fetches the server infos from a HTTP master server that publishes
+// its server list as JSON, like the DDNet servers.json.
+type HTTPSource struct {
+	url    string
+	client *http.Client
+}
+
+// SetHTTPClient replaces the default HTTP client that is used for the requests
+func (hs *HTTPSource) SetHTTPClient(client *http.Client) {
+	hs.client = client
+}
+
+// ServerList fetches and decodes the JSON server list.
+// All listed servers already contain their server info.
+func (hs *HTTPSource) ServerList(ctx context.Context) (ServerList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hs.url, nil)
+	if err != nil {
+		return ServerList{}, err
+	}
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return ServerList{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ServerList{}, fmt.Errorf("%w: unexpected status code %d", ErrInvalidResponseMessage, resp.StatusCode)
+	}
+
+	var list httpServerList
+	err = json.NewDecoder(resp.Body).Decode(&list)
+	if err != nil {
+		return ServerList{}, fmt.Errorf("%w: %w", ErrMalformedResponseData, err)
+	}
+
+	infos := make([]ServerInfo, 0, len(list.Servers))
+	for _, server := range list.Servers {
+		info, ok := server.serverInfo()
+		if !ok {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return ServerList{Infos: infos}, nil
+}
+
+// httpServerList is the JSON format of the HTTP master servers
+type httpServerList struct {
+	Servers []httpServer `json:"servers"`
+}
+
+type httpServer struct {
+	// urls like tw-0.6+udp://1.2.3.4:8303
+	Addresses []string       `json:"addresses"`
+	Location  string         `json:"location"`
+	Info      httpServerInfo `json:"info"`
+}
+
+type httpServerInfo struct {
+	MaxClients int    `json:"max_clients"`
+	MaxPlayers int    `json:"max_players"`
+	Passworded bool   `json:"passworded"`
+	GameType   string `json:"game_type"`
+	Name       string `json:"name"`
+	Map        struct {
+		Name string `json:"name"`
+	} `json:"map"`
+	Version string       `json:"version"`
+	Clients []httpPlayer `json:"clients"`
+}
+
+type httpPlayer struct {
+	Name     string      `json:"name"`
+	Clan     string      `json:"clan"`
+	Country  int         `json:"country"`
+	Score    int         `json:"score"`
+	IsPlayer bool        `json:"is_player"`
+	Afk      bool        `json:"afk"`
+	Skin     *PlayerSkin `json:"skin,omitempty"`
+}
+
+// serverInfo converts the JSON server into a ServerInfo.
+// Servers without any valid address are skipped.
+func (s *httpServer) serverInfo() (ServerInfo, bool) {
+	// servers are listed once per protocol version and IP address
+	addresses := make([]string, 0, len(s.Addresses))
+	for _, addr := range s.Addresses {
+		u, err := url.Parse(addr)
+		if err != nil || u.Host == "" || slices.Contains(addresses, u.Host) {
+			continue
+		}
+		addresses = append(addresses, u.Host)
+	}
+	if len(addresses) == 0 {
+		return ServerInfo{}, false
+	}
+
+	info := ServerInfo{
+		Version:    s.Info.Version,
+		Name:       s.Info.Name,
+		Map:        s.Info.Map.Name,
+		GameType:   s.Info.GameType,
+		MaxPlayers: s.Info.MaxPlayers,
+		NumClients: len(s.Info.Clients),
+		MaxClients: s.Info.MaxClients,
+		Players:    make(PlayerInfos, 0, len(s.Info.Clients)),
+	}
+	info.setEndpoints(addresses)
+	if s.Info.Passworded {
+		info.ServerFlags |= ServerFlagPassword
+	}
+
+	for _, c := range s.Info.Clients {
+		player := PlayerInfo{
+			Name:    c.Name,
+			Clan:    c.Clan,
+			Country: Country(c.Country),
+			Score:   c.Score,
+			Afk:     c.Afk,
+			Skin:    c.Skin,
+		}
+		if c.IsPlayer {
+			info.NumPlayers++
+		} else {
+			player.Type = PlayerTypeSpectator
+		}
+		info.Players = append(info.Players, player)
+	}
+	return info, true
+}
+
+// fetchServerLists fetches the server lists of all sources concurrently and merges them.
+// Addresses that belong to any endpoint of the server infos are removed from the addresses.
+// Sources that fail are skipped, an error is only returned if the context is done or all sources failed.
+func fetchServerLists(ctx context.Context, sources []ServerListSource) (ServerList, error) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		failed   int
+		infos    = make(map[netip.AddrPort]ServerInfo, 1024)
+		set      = make(map[netip.AddrPort]struct{}, 1024)
+		// endpoints contains all addresses of the server infos
+		endpoints = make(map[netip.AddrPort]struct{}, 1024)
+	)
+
+	wg.Add(len(sources))
+	for _, source := range sources {
+		go func(source ServerListSource) {
+			defer wg.Done()
+			list, err := source.ServerList(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			for _, addr := range list.Addresses {
+				set[normalizeAddrPort(addr)] = struct{}{}
+			}
+			for _, info := range list.Infos {
+				addr, err := netip.ParseAddrPort(info.Address)
+				if err != nil {
+					continue
+				}
+				infos[normalizeAddrPort(addr)] = info
+				for _, endpoint := range info.endpoints() {
+					addr, err := netip.ParseAddrPort(endpoint)
+					if err == nil {
+						endpoints[normalizeAddrPort(addr)] = struct{}{}
+					}
+				}
+			}
+		}(source)
+	}
+	wg.Wait()
+
+	result := ServerList{
+		Addresses: make([]netip.AddrPort, 0, len(set)),
+		Infos:     make([]ServerInfo, 0, len(infos)),
+	}
+	for addr := range set {
+		if _, ok := endpoints[addr]; ok {
+			continue
+		}
+		result.Addresses = append(result.Addresses, addr)
+	}
+	for _, info := range infos {
+		result.Infos = append(result.Infos, info)
+	}
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if failed > 0 && failed == len(sources) {
+		return result, firstErr
+	}
+	return result, nil
+}
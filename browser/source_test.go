@@ -0,0 +1,189 @@
+package browser_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// serversJSON is a shortened servers.json of a DDNet HTTP master server
+const serversJSON = `{
+	"servers": [
+		{
+			"addresses": ["tw-0.6+udp://10.0.0.1:8303", "tw-0.7+udp://10.0.0.1:8303"],
+			"location": "eu:de",
+			"info": {
+				"max_clients": 64,
+				"max_players": 64,
+				"passworded": true,
+				"game_type": "DDraceNetwork",
+				"name": "DDNet GER1",
+				"map": {"name": "Multeasymap", "sha256": "0000", "size": 4096},
+				"version": "0.6.4, 17.4",
+				"clients": [
+					{"name": "nameless tee", "clan": "DDNet", "country": 276, "score": -9999, "is_player": true, "afk": true, "skin": {"name": "default", "color_body": 1, "color_feet": 2}},
+					{"name": "brainless tee", "clan": "", "country": -1, "score": 120, "is_player": false}
+				]
+			}
+		},
+		{
+			"addresses": ["not an url"],
+			"info": {"name": "broken"}
+		}
+	]
+}`
+
+// newHTTPMaster starts a HTTP master server that is shut down at the end of the test
+func newHTTPMaster(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestHTTPSource(t *testing.T) {
+	t.Parallel()
+	s := newHTTPMaster(t, serversJSON)
+
+	source := browser.NewHTTPSource(s.URL)
+	source.SetHTTPClient(s.Client())
+
+	list, err := source.ServerList(context.Background())
+	require.NoError(t, err)
+	require.Len(t, 0, list.Addresses)
+	require.Len(t, 1, list.Infos)
+
+	info := list.Infos[0]
+	require.Equal(t, "10.0.0.1:8303", info.Address)
+	require.Equal(t, "DDNet GER1", info.Name)
+	require.Equal(t, "Multeasymap", info.Map)
+	require.Equal(t, browser.ServerFlagPassword, info.ServerFlags)
+	require.Equal(t, 1, info.NumPlayers)
+	require.Equal(t, 2, info.NumClients)
+	require.Len(t, 2, info.Players)
+	require.True(t, info.Players[0].Afk)
+	require.Equal(t, "default", info.Players[0].Skin.Name)
+	require.Equal(t, browser.PlayerTypeSpectator, info.Players[1].Type)
+}
+
+func TestHTTPSourceErrors(t *testing.T) {
+	t.Parallel()
+	broken := newHTTPMaster(t, `{"servers": [`)
+	_, err := browser.NewHTTPSource(broken.URL).ServerList(context.Background())
+	require.ErrorIs(t, browser.ErrMalformedResponseData, err)
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	_, err = browser.NewHTTPSource(missing.URL).ServerList(context.Background())
+	require.ErrorIs(t, browser.ErrInvalidResponseMessage, err)
+}
+
+func TestGetServerInfosSources(t *testing.T) {
+	t.Parallel()
+	udp := newServer(t, serverInfo("udp"))
+	m := newMaster(t, addrPort(t, udp.Addr))
+	h := newHTTPMaster(t, serversJSON)
+
+	sources := browser.WithSources(
+		browser.NewMasterSource(browser.Protocol07, m.Addr),
+		browser.NewHTTPSource(h.URL),
+		// failing sources are skipped
+		browser.NewHTTPSource(h.URL+"/missing.json"),
+	)
+
+	addresses, err := browser.GetServerAddressesContext(context.Background(), sources)
+	require.NoError(t, err)
+	require.Len(t, 2, addresses)
+
+	infos, err := browser.GetServerInfosContext(context.Background(), sources)
+	require.NoError(t, err)
+	require.Len(t, 2, infos)
+
+	names := make(map[string]string, len(infos))
+	for _, info := range infos {
+		names[info.Address] = info.Name
+	}
+	require.Equal(t, "udp", names[udp.Addr])
+	require.Equal(t, "DDNet GER1", names["10.0.0.1:8303"])
+	// the HTTP server info is not requested again
+	require.Equal(t, 1, udp.Requests())
+}
+
+func TestGetServerInfosContextPartialServerList(t *testing.T) {
+	t.Parallel()
+	degraded := newMaster(t, serverAddresses(200)...)
+	degraded.SetFaults(browsertest.MasterFaults{DropChunks: []int{1}})
+	h := newHTTPMaster(t, serversJSON)
+
+	sources := browser.WithSources(
+		browser.NewMasterSource(browser.Protocol07, degraded.Addr),
+		browser.NewHTTPSource(h.URL),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	infos, err := browser.GetServerInfosContext(ctx, sources)
+	require.ErrorIs(t, context.DeadlineExceeded, err)
+	require.Len(t, 1, infos)
+	require.Equal(t, "DDNet GER1", infos[0].Name)
+}
+
+func TestStreamServerInfosPartialServerList(t *testing.T) {
+	t.Parallel()
+	degraded := newMaster(t, serverAddresses(200)...)
+	degraded.SetFaults(browsertest.MasterFaults{DropChunks: []int{1}})
+	h := newHTTPMaster(t, serversJSON)
+
+	sources := browser.WithSources(
+		browser.NewMasterSource(browser.Protocol07, degraded.Addr),
+		browser.NewHTTPSource(h.URL),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	results := []browser.ScanResult{}
+	for result := range browser.StreamServerInfos(ctx, sources) {
+		results = append(results, result)
+	}
+
+	// the server info of the HTTP master, the addresses of the two received chunks and the error
+	require.Len(t, 1+125+1, results)
+	require.Equal(t, "DDNet GER1", results[0].Info.Name)
+	for _, result := range results[1 : len(results)-1] {
+		require.ErrorIs(t, context.DeadlineExceeded, result.Err)
+	}
+	last := results[len(results)-1]
+	require.Equal(t, "", last.Address)
+	require.ErrorIs(t, context.DeadlineExceeded, last.Err)
+}
+
+func TestGetServerAddressesSourcesEndpoints(t *testing.T) {
+	t.Parallel()
+	// the IPv6 endpoint of the HTTP server info is also listed by the UDP master server
+	m := newMaster(t, netip.MustParseAddrPort("[2001:db8::1]:8303"), netip.MustParseAddrPort("10.0.0.2:8303"))
+	h := newHTTPMaster(t, `{"servers": [{
+		"addresses": ["tw-0.7+udp://10.0.0.1:8303", "tw-0.7+udp://[2001:db8::1]:8303"],
+		"info": {"name": "dual stack", "map": {"name": "ctf5"}}
+	}]}`)
+
+	sources := browser.WithSources(
+		browser.NewMasterSource(browser.Protocol07, m.Addr),
+		browser.NewHTTPSource(h.URL),
+	)
+	addresses, err := browser.GetServerAddressesContext(context.Background(), sources)
+	require.NoError(t, err)
+	require.Len(t, 2, addresses)
+	for _, addr := range addresses {
+		require.True(t, addr.String() != "[2001:db8::1]:8303", "endpoint of the server info is listed again")
+	}
+}
@@ -2,6 +2,7 @@ package browser
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"fmt"
 	"time"
 )
@@ -11,10 +12,15 @@ import (
 func NewTokenRequestPacket() []byte {
 
 	n, _ := rand.Read(nil)
+	return newTokenRequestPacket(n)
+}
 
+// newTokenRequestPacket creates a token request packet with the passed client token
+// that the server sends back with every response
+func newTokenRequestPacket(clientToken int) []byte {
 	t := Token{
 		ServerToken: -1,
-		ClientToken: n,
+		ClientToken: clientToken,
 	}
 
 	header, _ := t.MarshalBinary()
@@ -44,6 +50,16 @@ func NewTokenRequestPacket() []byte {
 	return payload
 }
 
+// newClientToken creates a random client token
+func newClientToken() (int, error) {
+	var b [4]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(b[:])), nil
+}
+
 // Token is used to request information from either master of game servers.
 // The token needs to be renewed via NewTokenRequestPacket()
 // followed by parsing the server's response with NewToken(responseMessage []byte) (Token, error)
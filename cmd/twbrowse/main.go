@@ -0,0 +1,290 @@
+// Command twbrowse queries Teeworlds master servers and game servers.
+//
+// Usage:
+//
+//	twbrowse <command> [flags] [addresses...]
+//
+// Commands:
+//
+//	masters     show the health of the master servers
+//	count       count the unique game servers of all master servers
+//	addresses   list the addresses of all game servers
+//	info        query the server infos of the passed game server addresses
+//	servers     query the server infos of all game servers
+//
+// Examples:
+//
+//	twbrowse servers -filter 'gametype:ctf players>4 !password' -format csv
+//	twbrowse info -protocol ddnet -format json 1.2.3.4:8303
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+)
+
+const usage = `usage: twbrowse <command> [flags] [addresses...]
+
+commands:
+  masters     show the health of the master servers
+  count       count the unique game servers of all master servers
+  addresses   list the addresses of all game servers
+  info        query the server infos of the passed game server addresses
+  servers     query the server infos of all game servers
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "twbrowse: %v\n", err)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "twbrowse: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// config contains the parsed flags
+type config struct {
+	protocol browser.ProtocolVersion
+	timeout  time.Duration
+	retries  int
+	samples  int
+	format   string
+	filter   browser.Filter
+	masters  []string
+	httpURL  string
+	family   browser.AddressFamily
+	verbose  bool
+}
+
+// options returns the scanner options of the flags
+func (cfg *config) options() []browser.ScannerOption {
+	options := []browser.ScannerOption{
+		browser.WithProtocol(cfg.protocol),
+		browser.WithScanTimeout(cfg.timeout),
+		browser.WithRetries(cfg.retries),
+		browser.WithLatencySamples(cfg.samples),
+		browser.WithAddressFamily(cfg.family),
+	}
+
+	var sources []browser.ServerListSource
+	if len(cfg.masters) > 0 {
+		sources = append(sources, browser.NewMasterSource(cfg.protocol, cfg.masters...))
+	}
+	if cfg.httpURL != "" {
+		sources = append(sources, browser.NewHTTPSource(cfg.httpURL))
+	}
+	if len(sources) > 0 {
+		options = append(options, browser.WithSources(sources...))
+	}
+	return options
+}
+
+func parseFlags(args []string, stderr io.Writer) (*config, []string, error) {
+	var (
+		fs       = flag.NewFlagSet("twbrowse", flag.ContinueOnError)
+		cfg      = &config{}
+		protocol = fs.String("protocol", "0.7", "protocol version: 0.7, 0.6 or ddnet")
+		filter   = fs.String("filter", "", "filter query, e.g. 'gametype:ctf players>4 !password'")
+		masters  = fs.String("masters", "", "comma separated master server addresses instead of the default ones")
+		ipv4     = fs.Bool("4", false, "only use IPv4")
+		ipv6     = fs.Bool("6", false, "only use IPv6")
+	)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage+"\nflags:\n")
+		fs.PrintDefaults()
+	}
+	fs.DurationVar(&cfg.timeout, "timeout", browser.TimeoutServers, "maximum duration of a scan")
+	fs.IntVar(&cfg.retries, "retries", 2, "number of additional requests per server")
+	fs.IntVar(&cfg.samples, "samples", 1, "number of latency measurements per server")
+	fs.StringVar(&cfg.format, "format", "table", "output format: table, json or csv")
+	fs.StringVar(&cfg.httpURL, "http", "", "url of a HTTP master server, e.g. "+browser.DefaultHTTPMasterURL)
+	fs.BoolVar(&cfg.verbose, "v", false, "log the requests")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch strings.ToLower(*protocol) {
+	case "0.7", "7":
+		cfg.protocol = browser.Protocol07
+	case "0.6", "6":
+		cfg.protocol = browser.Protocol06
+	case "ddnet":
+		cfg.protocol = browser.ProtocolDDNet
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown protocol %q", errUsage, *protocol)
+	}
+
+	switch cfg.format {
+	case "table", "json", "csv":
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown format %q", errUsage, cfg.format)
+	}
+
+	switch {
+	case *ipv4 && *ipv6:
+		return nil, nil, fmt.Errorf("%w: -4 and -6 are mutually exclusive", errUsage)
+	case *ipv4:
+		cfg.family = browser.AddressFamilyIPv4
+	case *ipv6:
+		cfg.family = browser.AddressFamilyIPv6
+	}
+
+	cfg.filter, err = browser.ParseFilter(*filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range strings.Split(*masters, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			cfg.masters = append(cfg.masters, m)
+		}
+	}
+	return cfg, fs.Args(), nil
+}
+
+// run executes the command of the arguments and writes the result to stdout
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+	command := args[0]
+
+	cfg, addresses, err := parseFlags(args[1:], stderr)
+	if err != nil {
+		return err
+	}
+	browser.Logging = cfg.verbose
+
+	if command != "info" && len(addresses) > 0 {
+		return fmt.Errorf("%w: %s does not accept any addresses", errUsage, command)
+	}
+
+	// some margin for the master server requests
+	ctx, cancel := context.WithTimeout(ctx, 2*cfg.timeout)
+	defer cancel()
+
+	out := &output{w: stdout, format: cfg.format}
+	switch command {
+	case "masters":
+		return masters(ctx, cfg, out)
+	case "count":
+		list, err := browser.GetServerAddressesContext(ctx, cfg.options()...)
+		if err != nil && len(list) == 0 {
+			return err
+		}
+		return out.count(len(list))
+	case "addresses":
+		list, err := browser.GetServerAddressesContext(ctx, cfg.options()...)
+		if err != nil && len(list) == 0 {
+			return err
+		}
+		return out.addresses(list)
+	case "info":
+		if len(addresses) == 0 {
+			return fmt.Errorf("%w: info requires at least one address", errUsage)
+		}
+		return info(ctx, cfg, addresses, out, stderr)
+	case "servers":
+		infos, err := browser.GetServerInfosContext(ctx, cfg.options()...)
+		if err != nil && len(infos) == 0 {
+			return err
+		}
+		return out.servers(cfg.filter.Apply(infos))
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+}
+
+// masterStatus is the health report of a single master server
+type masterStatus struct {
+	Address    string  `json:"address"`
+	Reachable  bool    `json:"reachable"`
+	TokenRTT   float64 `json:"token_rtt_ms"`
+	Advertised int     `json:"advertised"`
+	Received   int     `json:"received"`
+	Unique     int     `json:"unique"`
+	ChunksLost int     `json:"chunks_lost"`
+	Degraded   bool    `json:"degraded"`
+	Err        string  `json:"error,omitempty"`
+}
+
+func masters(ctx context.Context, cfg *config, out *output) error {
+	reports, err := browser.GetMasterHealthContext(ctx, cfg.options()...)
+	if err != nil && len(reports) == 0 {
+		return err
+	}
+
+	results := make([]masterStatus, 0, len(reports))
+	for _, h := range reports {
+		status := masterStatus{
+			Address:    h.Address,
+			Reachable:  h.Reachable,
+			TokenRTT:   float64(h.TokenRTT.Microseconds()) / 1000,
+			Advertised: h.Advertised,
+			Received:   h.Received,
+			Unique:     h.Unique,
+			ChunksLost: h.ChunksLost(),
+			Degraded:   h.Degraded(),
+		}
+		if h.Err != nil {
+			status.Err = h.Err.Error()
+		}
+		results = append(results, status)
+	}
+	return out.masters(results)
+}
+
+// info prints the server infos of the addresses, errors are printed to stderr
+func info(ctx context.Context, cfg *config, addresses []string, out *output, stderr io.Writer) error {
+	s, err := browser.NewScanner(cfg.options()...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var (
+		infos  = make([]browser.ServerInfo, 0, len(addresses))
+		failed = 0
+	)
+	for result := range s.Stream(ctx, addresses...) {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(stderr, "%s: %v\n", result.Address, result.Err)
+			continue
+		}
+		infos = append(infos, result.Info)
+	}
+	err = out.servers(cfg.filter.Apply(infos))
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d servers failed", failed, len(addresses))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/jxsl13/twapi/browser"
+	"github.com/jxsl13/twapi/browser/browsertest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func newServer(t *testing.T, name, gameType string) *browsertest.Server {
+	t.Helper()
+	s, err := browsertest.NewServer(browser.ServerInfo{
+		Version:    "0.7.5",
+		Name:       name,
+		Map:        "ctf5",
+		GameType:   gameType,
+		MaxPlayers: 16,
+		MaxClients: 16,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+	return s
+}
+
+func runCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), args, &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestRun(t *testing.T) {
+	ctf := newServer(t, "ctf server", "CTF")
+	dm := newServer(t, "dm server", "DM")
+	m, err := browsertest.NewMaster(netip.MustParseAddrPort(ctf.Addr), netip.MustParseAddrPort(dm.Addr))
+	require.NoError(t, err)
+	defer m.Close()
+
+	out, err := runCommand(t, "count", "-masters", m.Addr)
+	require.NoError(t, err)
+	require.Equal(t, "2\n", out)
+
+	out, err = runCommand(t, "masters", "-masters", m.Addr, "-format", "json")
+	require.NoError(t, err)
+	var masters []masterStatus
+	require.NoError(t, json.Unmarshal([]byte(out), &masters))
+	require.Len(t, 1, masters)
+	require.Equal(t, m.Addr, masters[0].Address)
+	require.True(t, masters[0].Reachable)
+	require.Equal(t, 2, masters[0].Received)
+	require.False(t, masters[0].Degraded)
+
+	out, err = runCommand(t, "servers", "-masters", m.Addr, "-format", "csv", "-filter", "gametype:ctf")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, 2, lines)
+	require.Equal(t, "address,name,gametype,map,version,players,clients,flags,ping", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], ctf.Addr+",ctf server,CTF,ctf5,0.7.5,0/16,0/16,,"), lines[1])
+
+	out, err = runCommand(t, "info", dm.Addr)
+	require.NoError(t, err)
+	require.True(t, strings.Contains(out, "dm server"), out)
+
+	_, err = runCommand(t, "servers", "-protocol", "0.5")
+	require.ErrorIs(t, errUsage, err)
+	_, err = runCommand(t, "servers", "-filter", "unknown")
+	require.ErrorIs(t, browser.ErrInvalidQuery, err)
+	_, err = runCommand(t, "unknown")
+	require.ErrorIs(t, errUsage, err)
+}
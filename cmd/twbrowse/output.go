@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jxsl13/twapi/browser"
+)
+
+// output writes the results in the selected format
+type output struct {
+	w      io.Writer
+	format string
+}
+
+// write writes the rows as table or csv or the value as json
+func (o *output) write(value any, header []string, rows [][]string) error {
+	switch o.format {
+	case "json":
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case "csv":
+		w := csv.NewWriter(o.w)
+		_ = w.Write(header)
+		_ = w.WriteAll(rows)
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+		for _, row := range append([][]string{header}, rows...) {
+			for idx, column := range row {
+				if idx > 0 {
+					fmt.Fprint(w, "\t")
+				}
+				fmt.Fprint(w, column)
+			}
+			fmt.Fprintln(w)
+		}
+		return w.Flush()
+	}
+}
+
+func (o *output) count(n int) error {
+	if o.format == "table" {
+		_, err := fmt.Fprintln(o.w, n)
+		return err
+	}
+	return o.write(map[string]int{"servers": n}, []string{"servers"}, [][]string{{strconv.Itoa(n)}})
+}
+
+func (o *output) addresses(addresses []netip.AddrPort) error {
+	sort.Slice(addresses, func(i, j int) bool {
+		if addresses[i].Addr() != addresses[j].Addr() {
+			return addresses[i].Addr().Less(addresses[j].Addr())
+		}
+		return addresses[i].Port() < addresses[j].Port()
+	})
+	rows := make([][]string, 0, len(addresses))
+	for _, addr := range addresses {
+		rows = append(rows, []string{addr.String()})
+	}
+	return o.write(addresses, []string{"address"}, rows)
+}
+
+func (o *output) masters(masters []masterStatus) error {
+	rows := make([][]string, 0, len(masters))
+	for _, m := range masters {
+		rows = append(rows, []string{
+			m.Address,
+			strconv.FormatBool(m.Reachable),
+			strconv.FormatFloat(m.TokenRTT, 'f', 1, 64),
+			strconv.Itoa(m.Advertised),
+			strconv.Itoa(m.Received),
+			strconv.Itoa(m.Unique),
+			strconv.Itoa(m.ChunksLost),
+			strconv.FormatBool(m.Degraded),
+			m.Err,
+		})
+	}
+	header := []string{"address", "reachable", "token_rtt_ms", "advertised", "received", "unique", "chunks_lost", "degraded", "error"}
+	return o.write(masters, header, rows)
+}
+
+// servers writes the server infos sorted by the number of players
+func (o *output) servers(infos []browser.ServerInfo) error {
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].NumClients != infos[j].NumClients {
+			return infos[i].NumClients > infos[j].NumClients
+		}
+		return infos[i].Name < infos[j].Name
+	})
+
+	header := []string{"address", "name", "gametype", "map", "version", "players", "clients", "flags", "ping"}
+	rows := make([][]string, 0, len(infos))
+	for _, info := range infos {
+		ping := ""
+		if info.Latency != nil {
+			ping = info.Latency.Avg.Round(time.Millisecond).String()
+		}
+		rows = append(rows, []string{
+			info.Address,
+			info.Name,
+			info.GameType,
+			info.Map,
+			info.Version,
+			fmt.Sprintf("%d/%d", info.NumPlayers, info.MaxPlayers),
+			fmt.Sprintf("%d/%d", info.NumClients, info.MaxClients),
+			info.ServerFlags.String(),
+			ping,
+		})
+	}
+	return o.write(infos, header, rows)
+}
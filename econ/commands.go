@@ -0,0 +1,284 @@
+package econ
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twapi/econ/events"
+)
+
+// AuthLevel is the remote console authentication level of a client
+type AuthLevel int
+
+const (
+	AuthNone AuthLevel = iota
+	AuthHelper
+	AuthModerator
+	AuthAdmin
+)
+
+var authLevelNames = []string{"none", "helper", "moderator", "admin"}
+
+func (a AuthLevel) String() string {
+	if a >= 0 && int(a) < len(authLevelNames) {
+		return authLevelNames[a]
+	}
+	return strconv.Itoa(int(a))
+}
+
+// parseAuthLevel parses the (Admin), (Moderator), (Mod) and (Helper) suffixes of the status lines
+func parseAuthLevel(s string) AuthLevel {
+	s = strings.ToLower(strings.Trim(s, "() "))
+	switch s {
+	case "admin":
+		return AuthAdmin
+	case "moderator", "mod":
+		return AuthModerator
+	case "helper":
+		return AuthHelper
+	default:
+		return AuthNone
+	}
+}
+
+// Client is a connected client of the status command
+type Client struct {
+	ID      int
+	Address string
+	Name    string
+	Clan    string
+	Score   int
+	Auth    AuthLevel
+	// Connecting is set for clients that did not enter the game yet, their name is unknown.
+	Connecting bool
+}
+
+// BanEntry is a ban of the bans command
+type BanEntry struct {
+	Index int
+	// Address is a single ip or a range like 10.0.0.1-10.0.0.255
+	Address string
+	// Remaining is the rounded up time until the ban expires, zero for permanent bans
+	Remaining time.Duration
+	Reason    string
+}
+
+// Status returns the connected clients
+func (c *Conn) Status(ctx context.Context) ([]Client, error) {
+	lines, err := c.command(ctx, "status")
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make([]Client, 0, len(lines))
+	for _, l := range lines {
+		msg := events.ParseLine(l).Message
+		if !strings.HasPrefix(msg, "id=") {
+			continue
+		}
+		client, ok := parseClient(msg)
+		if ok {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+// Kick kicks the client with the id from the server
+func (c *Conn) Kick(ctx context.Context, id int, reason string) error {
+	_, err := c.command(ctx, fmt.Sprintf("kick %d", id)+optional(reason))
+	return err
+}
+
+// Ban bans the ip for the given minutes, zero minutes ban permanently
+func (c *Conn) Ban(ctx context.Context, ip string, minutes int, reason string) error {
+	_, err := c.command(ctx, fmt.Sprintf("ban %s %d", quote(ip), minutes)+optional(reason))
+	return err
+}
+
+// Unban removes the ban of the ip
+func (c *Conn) Unban(ctx context.Context, ip string) error {
+	_, err := c.command(ctx, "unban "+quote(ip))
+	return err
+}
+
+// Bans returns all bans of the server
+func (c *Conn) Bans(ctx context.Context) ([]BanEntry, error) {
+	lines, err := c.command(ctx, "bans")
+	if err != nil {
+		return nil, err
+	}
+
+	bans := make([]BanEntry, 0, len(lines))
+	for _, l := range lines {
+		m := banEntryLine.FindStringSubmatch(events.ParseLine(l).Message)
+		if m == nil {
+			continue
+		}
+		index, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[3])
+		bans = append(bans, BanEntry{
+			Index:     index,
+			Address:   strings.ReplaceAll(m[2], "'-'", "-"),
+			Remaining: time.Duration(minutes) * time.Minute,
+			Reason:    m[4],
+		})
+	}
+	return bans, nil
+}
+
+// Say sends a chat message from the server to all players
+func (c *Conn) Say(ctx context.Context, message string) error {
+	_, err := c.command(ctx, "say "+quote(message))
+	return err
+}
+
+// Broadcast shows the message in the center of the screen of all players
+func (c *Conn) Broadcast(ctx context.Context, message string) error {
+	_, err := c.command(ctx, "broadcast "+quote(message))
+	return err
+}
+
+// ChangeMap changes the current map, e.g. to ctf5
+func (c *Conn) ChangeMap(ctx context.Context, mapName string) error {
+	_, err := c.command(ctx, "change_map "+quote(mapName))
+	return err
+}
+
+// ForceVote forces a vote without any voting, e.g. ForceVote(ctx, "kick", "3", "spam").
+// The vote type is option, kick or spectate, the value is the vote description or the client id.
+func (c *Conn) ForceVote(ctx context.Context, voteType, value, reason string) error {
+	_, err := c.command(ctx, fmt.Sprintf("force_vote %s %s", quote(voteType), quote(value))+optional(reason))
+	return err
+}
+
+// command executes the command and returns ErrCommandFailed if the output contains an error
+func (c *Conn) command(ctx context.Context, cmd string) ([]string, error) {
+	lines, err := c.Exec(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lines {
+		msg := events.ParseLine(l).Message
+		lower := strings.ToLower(msg)
+		for _, prefix := range commandErrorPrefixes {
+			if strings.HasPrefix(lower, prefix) {
+				return lines, fmt.Errorf("%w: %s: %s", ErrCommandFailed, cmd, msg)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// beginnings of the error messages of the commands in lower case
+var commandErrorPrefixes = []string{
+	"no such command",
+	"invalid arguments",
+	"invalid client id",
+	"you can't kick yourself",
+	"kick not allowed",
+	"ban error",
+	"unban error",
+	"unban failed",
+	"invalid option",
+	"invalid network address",
+}
+
+// #0 '10.0.0.1' banned for 5 minutes (spam), #1 '10.0.0.1'-'10.0.0.255' banned for life (spam)
+var banEntryLine = regexp.MustCompile(`^#(\d+) '(.*)' banned for (?:(\d+) minutes?|life) \((.*)\)$`)
+
+// quote creates a string argument that may contain spaces, quotes and semicolons.
+// Free text must always be quoted, as the console splits unquoted commands at semicolons.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// optional returns the quoted argument with a leading space, an empty string if s is empty
+func optional(s string) string {
+	if s == "" {
+		return ""
+	}
+	return " " + quote(s)
+}
+
+// parseClient parses a status line, e.g.
+//
+//	id=0 addr=127.0.0.1:55555 client=0x0705 secure=yes name='nameless tee' clan='' score=0 (Admin)
+func parseClient(msg string) (Client, bool) {
+	var (
+		client Client
+		rest   = msg
+		hasID  bool
+	)
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		if rest == "" {
+			break
+		}
+		if rest[0] == '(' {
+			client.Auth = max(client.Auth, parseAuthLevel(rest))
+			break
+		}
+
+		var key, value string
+		key, rest, value = nextField(rest)
+		switch key {
+		case "id":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return client, false
+			}
+			client.ID = id
+			hasID = true
+		case "addr":
+			client.Address = strings.TrimSuffix(strings.TrimPrefix(value, "<{"), "}>")
+		case "name":
+			client.Name = value
+		case "clan":
+			client.Clan = value
+		case "score":
+			client.Score, _ = strconv.Atoi(value)
+		case "connecting":
+			client.Connecting = true
+		}
+	}
+	return client, hasID
+}
+
+// statusKey matches the beginning of the next field after a quoted value
+var statusKey = regexp.MustCompile(`^ (?:[a-z_]+=|\(|connecting)`)
+
+// nextField splits key=value or key='value' from the beginning of s.
+// Words without a value are returned as key.
+func nextField(s string) (key, rest, value string) {
+	end := strings.IndexAny(s, "= ")
+	if end < 0 {
+		return s, "", ""
+	}
+	if s[end] == ' ' {
+		return s[:end], s[end:], ""
+	}
+	key, s = s[:end], s[end+1:]
+
+	if !strings.HasPrefix(s, "'") {
+		end = strings.IndexByte(s, ' ')
+		if end < 0 {
+			return key, "", s
+		}
+		return key, s[end:], s[:end]
+	}
+
+	// quoted values may contain quotes, the value ends at the quote that is followed by the next field
+	s = s[1:]
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' && (i == len(s)-1 || statusKey.MatchString(s[i+1:])) {
+			return key, s[i+1:], s[:i]
+		}
+	}
+	return key, "", s
+}
@@ -0,0 +1,144 @@
+package econ_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/econ"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("status", func(args string) []string {
+		return []string{
+			// 0.6
+			"[Server]: id=0 addr=10.0.0.1:55555 name='nameless tee' score=3 (Admin)",
+			// 0.7
+			"[server]: id=1 addr=10.0.0.2:55555 client=0x0705 secure=yes name='it's a trap' clan='' score=-1 (Moderator)",
+			"[server]: id=2 addr=10.0.0.3:55555 connecting",
+			// DDNet
+			"2024-03-01 18:30:00 I server: id=3 addr=<{[2001:db8::1]:55555}> name='brainless' client=16050 secure=yes flags=3 key=helper (Helper)",
+			"[server]: unrelated line",
+		}
+	})
+	conn := dial(t, s)
+
+	clients, err := conn.Status(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []econ.Client{
+		{ID: 0, Address: "10.0.0.1:55555", Name: "nameless tee", Score: 3, Auth: econ.AuthAdmin},
+		{ID: 1, Address: "10.0.0.2:55555", Name: "it's a trap", Score: -1, Auth: econ.AuthModerator},
+		{ID: 2, Address: "10.0.0.3:55555", Connecting: true},
+		{ID: 3, Address: "[2001:db8::1]:55555", Name: "brainless", Auth: econ.AuthHelper},
+	}, clients)
+	require.Equal(t, "moderator", econ.AuthModerator.String())
+}
+
+func TestBans(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("bans", func(args string) []string {
+		return []string{
+			"[net_ban]: #0 '10.0.0.1' banned for 5 minutes (spam)",
+			"[net_ban]: #1 '10.0.0.1'-'10.0.0.255' banned for life (cheating)",
+			"[net_ban]: #2 '10.0.0.2' banned for 1 minute (No reason given)",
+			"[net_ban]: 3 ban(s)",
+		}
+	})
+	conn := dial(t, s)
+
+	bans, err := conn.Bans(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []econ.BanEntry{
+		{Index: 0, Address: "10.0.0.1", Remaining: 5 * time.Minute, Reason: "spam"},
+		{Index: 1, Address: "10.0.0.1-10.0.0.255", Reason: "cheating"},
+		{Index: 2, Address: "10.0.0.2", Remaining: time.Minute, Reason: "No reason given"},
+	}, bans)
+}
+
+func TestAdminCommands(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	ok := func(args string) []string { return nil }
+	for _, cmd := range []string{"ban", "unban", "say", "broadcast", "change_map", "force_vote"} {
+		s.Handle(cmd, ok)
+	}
+	s.Handle("kick", func(args string) []string {
+		if args == "9" {
+			return []string{"[server]: invalid client id to kick"}
+		}
+		return nil
+	})
+	conn := dial(t, s)
+
+	ctx := context.Background()
+	require.NoError(t, conn.Kick(ctx, 1, "spam"))
+	require.ErrorIs(t, econ.ErrCommandFailed, conn.Kick(ctx, 9, ""))
+	require.NoError(t, conn.Ban(ctx, "10.0.0.1", 5, "spam"))
+	require.NoError(t, conn.Unban(ctx, "10.0.0.1"))
+	require.NoError(t, conn.Say(ctx, "hello world"))
+	require.NoError(t, conn.Broadcast(ctx, "restart in 5 minutes"))
+	require.NoError(t, conn.ChangeMap(ctx, "ctf5"))
+	require.NoError(t, conn.ForceVote(ctx, "option", `say "hi"`, "fun"))
+
+	// the fake server does not know the command
+	require.ErrorIs(t, econ.ErrCommandFailed, func() error {
+		_, err := conn.Status(ctx)
+		return err
+	}())
+
+	commands := []string{}
+	for _, cmd := range s.Commands() {
+		if len(cmd) < 5 || cmd[:5] != "echo " {
+			commands = append(commands, cmd)
+		}
+	}
+	require.Equal(t, []string{
+		`kick 1 "spam"`,
+		"kick 9",
+		`ban "10.0.0.1" 5 "spam"`,
+		`unban "10.0.0.1"`,
+		`say "hello world"`,
+		`broadcast "restart in 5 minutes"`,
+		`change_map "ctf5"`,
+		`force_vote "option" "say \"hi\"" "fun"`,
+		"status",
+	}, commands)
+}
+
+func TestCommandInjection(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	ok := func(args string) []string { return nil }
+	for _, cmd := range []string{"kick", "ban", "say", "broadcast", "change_map", "force_vote"} {
+		s.Handle(cmd, ok)
+	}
+	conn := dial(t, s)
+
+	ctx := context.Background()
+	require.NoError(t, conn.Kick(ctx, 1, "bye; shutdown"))
+	require.NoError(t, conn.Ban(ctx, "10.0.0.1", 5, `spam" ; shutdown #`))
+	require.NoError(t, conn.Say(ctx, "hi; shutdown"))
+	require.NoError(t, conn.Broadcast(ctx, `\"; shutdown`))
+	require.NoError(t, conn.ChangeMap(ctx, "ctf5; shutdown"))
+	require.NoError(t, conn.ForceVote(ctx, "kick", "3", "# ; shutdown"))
+
+	commands := []string{}
+	for _, cmd := range s.Commands() {
+		if !strings.HasPrefix(cmd, "echo ") {
+			commands = append(commands, cmd)
+		}
+	}
+	require.Equal(t, []string{
+		`kick 1 "bye; shutdown"`,
+		`ban "10.0.0.1" 5 "spam\" ; shutdown #"`,
+		`say "hi; shutdown"`,
+		`broadcast "\\\"; shutdown"`,
+		`change_map "ctf5; shutdown"`,
+		`force_vote "kick" "3" "# ; shutdown"`,
+	}, commands)
+}
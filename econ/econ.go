@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/jxsl13/twapi/internal"
@@ -13,6 +14,29 @@ import (
 
 var (
 	ErrAuthenticationFailed = errors.New("authentication failed")
+	// ErrInvalidCommand is returned by Exec for commands that span multiple lines
+	ErrInvalidCommand = errors.New("invalid command")
+	// ErrCommandFailed is returned by the admin commands if the server printed an error message
+	ErrCommandFailed = errors.New("command failed")
+	// ErrConnectionLost is returned by Exec if the connection was lost before the output was read
+	ErrConnectionLost = errors.New("connection lost")
+	// ErrClosed is returned after the connection was closed
+	ErrClosed = errors.New("connection closed")
+	// ErrNotConnected is returned by the Manager for servers that are not connected yet
+	ErrNotConnected = errors.New("not connected")
+	// ErrUnknownServer is returned by the Manager for names that were not added
+	ErrUnknownServer = errors.New("unknown server")
+	// ErrServerExists is returned by Manager.Add if the name is already taken
+	ErrServerExists = errors.New("server already exists")
+	// ErrReadLineDisabled is returned by ReadLine if the buffer was disabled with WithReadLineBuffer(0)
+	ErrReadLineDisabled = errors.New("read line disabled")
+)
+
+const (
+	// subscriptionBuffer is the capacity of the subscription channels
+	subscriptionBuffer = 64
+	// defaultReadLineBuffer is the default number of lines that are buffered for ReadLine
+	defaultReadLineBuffer = 1024
 )
 
 // DialTo creates a new econ connection that can be used to write or read lines from
@@ -23,165 +47,291 @@ var (
 // any of the 4 existing econ slots.
 // You can also set your ec_bantime to anything other than 0 in order to ban people that try to connect to you external console and try incorrect credentials
 // ec_output_level [1,2] allows to increase the logging level of your external console. This allows for more verbose econ output parsing
+//
+// A single goroutine reads all lines and reconnects if the connection is lost.
+// The lines are buffered for ReadLine from the moment DialTo returns, see WithReadLineBuffer.
+// The connection is closed when the context of WithContext is done.
 func DialTo(address, password string, options ...Option) (conn *Conn, err error) {
 
 	c := &Conn{
 		ctx:               context.Background(),
-		telnetConn:        nil,
 		address:           address,
 		password:          password,
 		maxReconnectDelay: 10 * time.Second,
+		subscriptions:     make(map[*subscription]struct{}),
+		readLineBuffer:    defaultReadLineBuffer,
 	}
 
-	defer func() {
-		if err != nil {
-			_ = c.Close()
-		}
-	}()
-
 	for _, option := range options {
 		option(c)
 	}
-	c.ctx, c.cancel = context.WithCancel(c.ctx)
+	if c.readLineBuffer > 0 {
+		c.lines = newLineQueue(c.readLineBuffer)
+	}
+	parent := c.ctx
+	c.ctx, c.cancel = context.WithCancel(parent)
 
-	err = c.reconnect()
+	tc, err := c.dial()
 	if err != nil {
+		c.cancel()
 		return nil, err
 	}
+	c.telnetConn = tc
+	c.generation = 1
+	c.ready = make(chan struct{})
+	close(c.ready)
+
+	c.stopAfterFunc = context.AfterFunc(parent, func() {
+		c.shutdown(parent.Err())
+	})
+
+	c.wg.Add(1)
+	go c.read(tc)
 	return c, nil
 }
 
-// Conn is the telnet connection to a teeworlds external console terminal(econ)
+// Conn is the telnet connection to a teeworlds external console terminal(econ).
+// It is safe for concurrent use.
 type Conn struct {
 	ctx               context.Context
 	cancel            context.CancelFunc
-	telnetConn        *telnet.Conn
+	stopAfterFunc     func() bool
 	address           string
 	password          string
 	maxReconnectDelay time.Duration
 	authCommandList   []string
+
+	// wg waits for the reader goroutine
+	wg sync.WaitGroup
+
+	// wmu serializes the writes
+	wmu sync.Mutex
+
+	mu sync.Mutex
+	// telnetConn is nil while reconnecting
+	telnetConn *telnet.Conn
+	// generation is incremented with every reconnect
+	generation int
+	// ready is closed as soon as the connection is established or err is set
+	ready chan struct{}
+	// err is set when the connection is closed for good
+	err     error
+	waiters []*execWaiter
+
+	subMu         sync.Mutex
+	subscriptions map[*subscription]struct{}
+	subsClosed    bool
+
+	// lines buffers the lines for ReadLine, nil if ReadLine is disabled
+	lines          *lineQueue
+	readLineBuffer int
 }
 
 // Close must be called when the connection is to be quit
-func (c *Conn) Close() (err error) {
-	c.cancel()
-	if c.telnetConn != nil {
-		_ = c.logout()
-		err = c.telnetConn.Close()
-		c.telnetConn = nil
-	}
-	return err
+func (c *Conn) Close() error {
+	return c.shutdown(ErrClosed)
 }
 
-func (c *Conn) logout() error {
-	return c.unguardedWriteLine("logout")
+// Err returns the reason why the connection was closed, nil while it is open
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
 }
 
-// ReadLine reads a line from the external console
-// if the connection is lost, it attempts to reconnect multiple times before
-// trying to read the line again.
-func (c *Conn) ReadLine() (line string, err error) {
-	err = c.guard(func() (err error) {
-		line, err = c.unguardedReadLine()
-		return err
-	})
-	return line, err
+// Connected returns true while the connection is established.
+// It returns false while the reader reconnects and after the connection was closed.
+func (c *Conn) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.telnetConn != nil && c.err == nil
 }
 
-func (c *Conn) guard(f func() error) error {
+// Reconnects returns the number of times the connection was re-established
+func (c *Conn) Reconnects() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return max(0, c.generation-1)
+}
 
-	// try without retry overhead
-	err := f()
-	if err == nil {
-		return nil
+// shutdown logs out, closes the connection and waits for the reader to finish
+func (c *Conn) shutdown(reason error) (err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = reason
+	}
+	c.mu.Unlock()
+	c.cancel()
+	c.stopAfterFunc()
+
+	c.wmu.Lock()
+	c.mu.Lock()
+	tc := c.telnetConn
+	c.telnetConn = nil
+	c.mu.Unlock()
+	if tc != nil {
+		_ = writeLines(tc, "logout")
+		err = tc.Close()
 	}
+	c.wmu.Unlock()
 
-	// add retry overhead
-	return c.retry(func() (retry bool, err error) {
-		err = c.reconnect()
+	c.wg.Wait()
+	return err
+}
+
+// ReadLine reads the next line from the external console, except for the output of Exec.
+// All lines that were read since DialTo returned are buffered until they are read with ReadLine.
+// If ReadLine falls behind by more lines than the buffer of WithReadLineBuffer holds,
+// the oldest lines are discarded. Use Subscribe in order not to lose any line.
+// If the connection is lost, the reader reconnects before reading further lines.
+func (c *Conn) ReadLine() (line string, err error) {
+	if c.lines == nil {
+		return "", ErrReadLineDisabled
+	}
+	line, ok := c.lines.pop()
+	if !ok {
+		return "", c.Err()
+	}
+	return line, nil
+}
+
+// WriteLine writes a line to the external console and forces its execution by appending a \n
+// If the connection is lost, the line is written again after reconnecting.
+func (c *Conn) WriteLine(line string) (err error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	after := 0
+	for {
+		tc, generation, err := c.conn(c.ctx, after)
 		if err != nil {
-			return false, err
+			return err
 		}
-		err = f()
+		err = writeLines(tc, line)
 		if err == nil {
-			return false, nil
+			return nil
 		}
-		return true, err
-	})
+		// the reader notices the closed connection and reconnects
+		c.invalidate(tc)
+		after = generation
+	}
 }
 
-// no reconnect mechanisms guard this line reading
-func (c *Conn) unguardedReadLine() (string, error) {
-	if c.telnetConn == nil {
-		return "", errors.New("telnet connection is nil")
+// conn returns the current connection with a generation greater than after.
+// It waits while the reader reconnects.
+func (c *Conn) conn(ctx context.Context, after int) (*telnet.Conn, int, error) {
+	for {
+		c.mu.Lock()
+		var (
+			tc         = c.telnetConn
+			generation = c.generation
+			ready      = c.ready
+			err        = c.err
+		)
+		c.mu.Unlock()
+
+		switch {
+		case err != nil:
+			return nil, 0, err
+		case tc != nil && generation > after:
+			return tc, generation, nil
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-c.ctx.Done():
+			return nil, 0, ErrClosed
+		}
 	}
+}
 
-	stackArray := [256]byte{}
-	stackArraySlice := stackArray[:0]
-	lineBuffer := bytes.NewBuffer(stackArraySlice)
+// invalidate closes the broken connection, conn waits for the reader to reconnect afterwards
+func (c *Conn) invalidate(tc *telnet.Conn) {
+	c.mu.Lock()
+	if c.telnetConn == tc {
+		c.telnetConn = nil
+		c.ready = make(chan struct{})
+	}
+	c.mu.Unlock()
+	_ = tc.Close()
+}
 
-	singleCharBuffer := [1]byte{}
-	singleCharBufferSlice := singleCharBuffer[:]
+// read is the only goroutine that reads from the connection
+func (c *Conn) read(tc *telnet.Conn) {
+	defer c.wg.Done()
+	defer c.closeSubscriptions()
 
-	// we read single byte arrays until we hit a linebreak
 	for {
-		n, err := c.telnetConn.Read(singleCharBufferSlice)
-		if err != nil {
-			return "", err
-		}
-		// failed to read one byte
-		if n == 0 {
+		line, err := readLine(tc)
+		if err == nil {
+			c.dispatch(line)
 			continue
 		}
-		// we do hit a linebreak
-		// we expect the next two characters to be 0xFF
-		if singleCharBuffer[0] == '\n' {
-			buffer := [2]byte{0xFF, 0xFF} // explicitly initialize with non zero value
-			bufferSlice := buffer[:]
-
-			// seemingly every line ends with two \x00\x00
-			n, err = c.telnetConn.Read(bufferSlice)
-			if err != nil {
-				return "", err
-			}
-			if n != 2 || !bytes.Equal(bufferSlice, []byte{0x00, 0x00}) {
-				return "", errors.New("failed to read \\x00\\x00")
-			}
 
-			// successfully got the two 0x00,
-			// no need to append newline characters here
-			break
+		var ok bool
+		tc, ok = c.reconnect(tc)
+		if !ok {
+			return
 		}
-		// n == 1 && buffer[0] != '\n'
-		lineBuffer.WriteByte(singleCharBuffer[0])
 	}
-
-	return lineBuffer.String(), nil
 }
 
-// WriteLine writes a line to the external console and forces its execution by appending a \n
-func (c *Conn) WriteLine(line string) (err error) {
-	return c.guard(func() error {
-		return c.unguardedWriteLine(line)
-	})
-}
+// reconnect replaces the broken connection, the pending Exec calls fail with ErrConnectionLost.
+// It returns false if the connection is closed for good.
+func (c *Conn) reconnect(old *telnet.Conn) (*telnet.Conn, bool) {
+	c.invalidate(old)
 
-// WriteLine writes a line to the external console and forces its execution by appending a \n
-func (c *Conn) unguardedWriteLine(line string) error {
-	if c.telnetConn == nil {
-		return errors.New("telnet connection is nil")
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, false
 	}
+	waiters := c.waiters
+	c.waiters = nil
+	c.mu.Unlock()
 
-	stream := []byte(line + "\n")
+	for _, w := range waiters {
+		w.finish(ErrConnectionLost)
+	}
 
-	for len(stream) > 0 {
-		n, err := c.telnetConn.Write(stream)
-		if err != nil {
-			return err
+	tc, err := c.dial()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer close(c.ready)
+	if c.err == nil && err != nil {
+		c.err = err
+	}
+	if c.err != nil {
+		if tc != nil {
+			_ = tc.Close()
 		}
-		stream = stream[n:]
+		return nil, false
 	}
-	return nil
+	c.telnetConn = tc
+	c.generation++
+	return tc, true
+}
+
+// dial connects and authenticates until it succeeds, the authentication fails or the context is done
+func (c *Conn) dial() (tc *telnet.Conn, err error) {
+	err = c.retry(func() (retry bool, err error) {
+		conn, err := telnet.DialTo(c.address)
+		if err != nil {
+			return true, err
+		}
+
+		err = c.authenticate(conn)
+		if err != nil {
+			_ = conn.Close()
+			return !errors.Is(err, ErrAuthenticationFailed), err
+		}
+		tc = conn
+		return false, nil
+	})
+	return tc, err
 }
 
 func (c *Conn) retry(f func() (bool, error)) error {
@@ -213,57 +363,11 @@ func (c *Conn) retry(f func() (bool, error)) error {
 	}
 }
 
-func (c *Conn) connect() error {
-	// reconnect tcp connection
-	telnetConn, err := telnet.DialTo(c.address)
-	if err != nil {
-		return err
-	}
-
-	// update internal state
-	c.telnetConn = telnetConn
-	return nil
-}
-
-func (c *Conn) reconnect() error {
-
-	if c.telnetConn != nil {
-		_ = c.logout()
-		_ = c.telnetConn.Close()
-	}
-
-	// keep track of the last error that was returned
-	return c.retry(func() (retry bool, err error) {
-		err = c.connect()
-		if err != nil {
-			return true, err
-		}
-		defer func() {
-			if err != nil {
-				c.telnetConn.Close()
-				c.telnetConn = nil
-			}
-		}()
-
-		err = c.authenticate()
-		if err == nil {
-			return false, nil
-		}
-
-		if errors.Is(err, ErrAuthenticationFailed) {
-			return false, err
-		}
-
-		return true, err
-	})
-
-}
-
 // authenticate in the external console
-func (c *Conn) authenticate() (err error) {
+func (c *Conn) authenticate(tc *telnet.Conn) (err error) {
 	password := c.password
 
-	line, err := c.unguardedReadLine()
+	line, err := readLine(tc)
 	if err != nil {
 		// forward network error
 		return err
@@ -273,13 +377,13 @@ func (c *Conn) authenticate() (err error) {
 		return fmt.Errorf("%w: could not find password request line: %s", ErrAuthenticationFailed, line)
 	}
 
-	err = c.unguardedWriteLine(password)
+	err = writeLines(tc, password)
 	if err != nil {
 		// forward network error
 		return err
 	}
 
-	line, err = c.unguardedReadLine()
+	line, err = readLine(tc)
 	if err != nil {
 		// forward network error
 		return err
@@ -289,10 +393,65 @@ func (c *Conn) authenticate() (err error) {
 		return fmt.Errorf("%w: %s", ErrAuthenticationFailed, line)
 	}
 
-	for _, cmd := range c.authCommandList {
-		err = c.unguardedWriteLine(cmd)
+	return writeLines(tc, c.authCommandList...)
+}
+
+// readLine reads a single line without any reconnect mechanisms
+func readLine(tc *telnet.Conn) (string, error) {
+	stackArray := [256]byte{}
+	stackArraySlice := stackArray[:0]
+	lineBuffer := bytes.NewBuffer(stackArraySlice)
+
+	singleCharBuffer := [1]byte{}
+	singleCharBufferSlice := singleCharBuffer[:]
+
+	// we read single byte arrays until we hit a linebreak
+	for {
+		n, err := tc.Read(singleCharBufferSlice)
 		if err != nil {
-			return err
+			return "", err
+		}
+		// failed to read one byte
+		if n == 0 {
+			continue
+		}
+		// we do hit a linebreak
+		// we expect the next two characters to be 0xFF
+		if singleCharBuffer[0] == '\n' {
+			buffer := [2]byte{0xFF, 0xFF} // explicitly initialize with non zero value
+			bufferSlice := buffer[:]
+
+			// seemingly every line ends with two \x00\x00
+			n, err = tc.Read(bufferSlice)
+			if err != nil {
+				return "", err
+			}
+			if n != 2 || !bytes.Equal(bufferSlice, []byte{0x00, 0x00}) {
+				return "", errors.New("failed to read \\x00\\x00")
+			}
+
+			// successfully got the two 0x00,
+			// no need to append newline characters here
+			break
+		}
+		// n == 1 && buffer[0] != '\n'
+		lineBuffer.WriteByte(singleCharBuffer[0])
+	}
+
+	return lineBuffer.String(), nil
+}
+
+// writeLines writes the lines and forces their execution by appending a \n to each of them
+func writeLines(tc *telnet.Conn, lines ...string) error {
+	for _, line := range lines {
+		stream := []byte(line + "\n")
+
+		for len(stream) > 0 {
+			n, err := tc.Write(stream)
+			if err != nil {
+				return err
+			}
+			stream = stream[n:]
 		}
 	}
 	return nil
@@ -0,0 +1,201 @@
+// Package econtest provides a fake Teeworlds external console (econ) server on a local TCP port.
+// It allows to test the econ package without any running game server.
+package econtest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	passwordRequest       = "Enter password:"
+	authenticationSuccess = "Authentication successful. External console access granted."
+	authenticationFailure = "Wrong password."
+)
+
+// Handler creates the output lines of a command, e.g. "[server]: id=0 addr=...".
+// args is the text after the command name.
+type Handler func(args string) []string
+
+// NewServer starts a fake econ server on a random local TCP port that accepts the passed password.
+// The echo command is answered like by a real server, any other command must be registered with Handle.
+func NewServer(password string) (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Addr:     l.Addr().String(),
+		l:        l,
+		password: password,
+		handlers: make(map[string]Handler),
+		conns:    make(map[*conn]struct{}),
+	}
+	s.handlers["echo"] = func(args string) []string {
+		return []string{"[Console]: " + args}
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Server is a fake econ server
+type Server struct {
+	// Addr is the ip:port address that the econ server listens on
+	Addr string
+
+	l        net.Listener
+	password string
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	conns    map[*conn]struct{}
+	commands []string
+	logins   int
+}
+
+// Handle registers the handler of a command
+func (s *Server) Handle(command string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = handler
+}
+
+// Send prints a log line on all authenticated connections
+func (s *Server) Send(line string) {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		_ = c.writeLine(line)
+	}
+}
+
+// Commands returns all commands that were received from authenticated connections
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Logins returns the number of successful authentications
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// Connections returns the number of authenticated connections
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Disconnect closes all client connections, e.g. in order to test reconnects
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		_ = c.Close()
+		delete(s.conns, c)
+	}
+}
+
+// Close shuts down the server and closes all client connections
+func (s *Server) Close() error {
+	err := s.l.Close()
+	s.Disconnect()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(&conn{Conn: nc})
+		}()
+	}
+}
+
+func (s *Server) handle(c *conn) {
+	defer c.Close()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewScanner(c)
+	if c.writeLine(passwordRequest) != nil || !r.Scan() {
+		return
+	}
+	if strings.TrimRight(r.Text(), "\r") != s.password {
+		_ = c.writeLine(authenticationFailure)
+		return
+	}
+
+	s.mu.Lock()
+	s.logins++
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	if c.writeLine(authenticationSuccess) != nil {
+		return
+	}
+
+	for r.Scan() {
+		line := strings.TrimRight(r.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if line == "logout" {
+			return
+		}
+
+		name, args, _ := strings.Cut(line, " ")
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		handler, ok := s.handlers[name]
+		s.mu.Unlock()
+
+		output := []string{"[Console]: No such command: " + name + "."}
+		if ok {
+			output = handler(args)
+		}
+		for _, out := range output {
+			if c.writeLine(out) != nil {
+				return
+			}
+		}
+	}
+}
+
+// conn serializes the writes of the handler and Send
+type conn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+// writeLine writes the line with the framing of the econ server
+func (c *conn) writeLine(line string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.Write([]byte(line + "\n\x00\x00"))
+	return err
+}
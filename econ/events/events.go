@@ -0,0 +1,227 @@
+// Package events parses the lines of the external console into typed events.
+//
+//	p := events.NewParser()
+//	for {
+//		line, err := conn.ReadLine()
+//		if err != nil {
+//			return err
+//		}
+//		switch e := p.Parse(line).(type) {
+//		case *events.Chat:
+//			fmt.Println(e.Player.Name, e.Text)
+//		case *events.Kill:
+//			fmt.Println(e.Killer.Name, "killed", e.Victim.Name, "with", e.Weapon)
+//		}
+//	}
+//
+// Mods can register their own line patterns with Parser.Register.
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// Event is a parsed console line
+type Event interface {
+	// RawLine returns the console line that the event was parsed from
+	RawLine() Line
+}
+
+// Line is a console line split into its parts.
+// Every event embeds the line it was parsed from.
+type Line struct {
+	// Time is the timestamp of the line, zero if the server does not send any
+	Time time.Time
+	// Level is the DDNet log level, e.g. I, W or E, empty for vanilla servers
+	Level string
+	// System is the part of the server that printed the line, e.g. server, game or chat
+	System string
+	// Message is the text after the system
+	Message string
+	// Raw is the unparsed line
+	Raw string
+}
+
+// RawLine returns the line itself
+func (l Line) RawLine() Line {
+	return l
+}
+
+// Unknown is a line that no registered pattern matched
+type Unknown struct {
+	Line
+}
+
+// Player identifies a player by its client id and name
+type Player struct {
+	ID   int
+	Name string
+}
+
+func (p Player) String() string {
+	return fmt.Sprintf("%d:%s", p.ID, p.Name)
+}
+
+// Weapon is the weapon of a kill
+type Weapon int
+
+const (
+	// WeaponGame is used when the game kills a player, e.g. on a team change
+	WeaponGame Weapon = -3
+	// WeaponSelf is used when a player kills itself
+	WeaponSelf Weapon = -2
+	// WeaponWorld is used when a player dies in a death tile
+	WeaponWorld   Weapon = -1
+	WeaponHammer  Weapon = 0
+	WeaponGun     Weapon = 1
+	WeaponShotgun Weapon = 2
+	WeaponGrenade Weapon = 3
+	WeaponLaser   Weapon = 4
+	WeaponNinja   Weapon = 5
+)
+
+var weaponNames = map[Weapon]string{
+	WeaponGame:    "game",
+	WeaponSelf:    "self",
+	WeaponWorld:   "world",
+	WeaponHammer:  "hammer",
+	WeaponGun:     "gun",
+	WeaponShotgun: "shotgun",
+	WeaponGrenade: "grenade",
+	WeaponLaser:   "laser",
+	WeaponNinja:   "ninja",
+}
+
+// String returns the name of the weapon or its number for unknown weapons
+func (w Weapon) String() string {
+	if name, ok := weaponNames[w]; ok {
+		return name
+	}
+	return fmt.Sprint(int(w))
+}
+
+// ClientEntered is sent when a client finished connecting and entered the game
+type ClientEntered struct {
+	Line
+	ID      int
+	Address string
+}
+
+// ClientDropped is sent when a client disconnected or was kicked
+type ClientDropped struct {
+	Line
+	ID      int
+	Address string
+	Reason  string
+}
+
+// TeamJoin is sent when a player enters the game or changes its team
+type TeamJoin struct {
+	Line
+	Player Player
+	Team   int
+}
+
+// Leave is sent when a player leaves the game
+type Leave struct {
+	Line
+	Player Player
+}
+
+// Chat is a public chat message of a player
+type Chat struct {
+	Line
+	Player Player
+	Team   int
+	Text   string
+}
+
+// TeamChat is a team chat message of a player
+type TeamChat struct {
+	Line
+	Player Player
+	Team   int
+	Text   string
+}
+
+// ServerChat is a chat message of the server, e.g. a broadcast of the say command
+type ServerChat struct {
+	Line
+	Text string
+}
+
+// Kill is sent when a player dies
+type Kill struct {
+	Line
+	Killer Player
+	Victim Player
+	Weapon Weapon
+	// Special is a bit mask of special kill conditions, e.g. 1 if the victim carried the flag
+	Special int
+}
+
+// FlagGrab is sent when a player takes the flag
+type FlagGrab struct {
+	Line
+	Player Player
+	// Team is the team of the flag, -1 for servers that do not send it
+	Team int
+}
+
+// FlagCapture is sent when a player captures the flag
+type FlagCapture struct {
+	Line
+	Player Player
+	// Team is the team of the flag, -1 for servers that do not send it
+	Team int
+}
+
+// MapChange is sent when the server loaded a map
+type MapChange struct {
+	Line
+	Map string
+}
+
+// VoteStart is sent when a player calls a vote
+type VoteStart struct {
+	Line
+	Player Player
+	// Type is the kind of the vote, e.g. kick, spectate or option
+	Type        string
+	Description string
+	Reason      string
+	Command     string
+	Forced      bool
+}
+
+// VoteEnd is sent when a vote passed or failed
+type VoteEnd struct {
+	Line
+	Passed bool
+}
+
+// RconLogin is sent when a client authenticated in the remote console
+type RconLogin struct {
+	Line
+	ID int
+	// Key is the name of the authentication key, empty for servers without keys
+	Key string
+	// Level is the authentication level, e.g. admin or moderator
+	Level string
+}
+
+// Ban is sent when an address was banned
+type Ban struct {
+	Line
+	Address string
+	// Duration is zero for permanent bans
+	Duration time.Duration
+	Reason   string
+}
+
+// Unban is sent when an address was unbanned
+type Unban struct {
+	Line
+	Address string
+}
@@ -0,0 +1,231 @@
+package events
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+var (
+	// [2024-01-02 15:04:05][server]: message or [server]: message
+	vanillaLine = regexp.MustCompile(`^(?:\[([^\]]*)\])?\[([^\]]+)\]: ?(.*)$`)
+	// 2024-01-02 15:04:05 I server: message
+	ddnetLine = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) ([A-Z]) ([^:]+): ?(.*)$`)
+)
+
+// ParseFunc creates an event from a line and the submatches of the pattern.
+// match[0] is the whole message, match[1:] are the capture groups.
+// Returning nil lets the next matching pattern parse the line.
+type ParseFunc func(line Line, match []string) Event
+
+type pattern struct {
+	system string
+	re     *regexp.Regexp
+	parse  ParseFunc
+}
+
+// NewParser creates a parser that knows the lines of vanilla Teeworlds 0.6, 0.7 and DDNet servers
+func NewParser() *Parser {
+	p := &Parser{}
+	for _, d := range defaultPatterns {
+		p.MustRegister(d.system, d.expr, d.parse)
+	}
+	return p
+}
+
+// Parser converts console lines into events.
+// The zero value does not know any line, use NewParser for the default patterns.
+// A Parser is safe for concurrent use.
+type Parser struct {
+	mu       sync.RWMutex
+	patterns []pattern
+}
+
+// Register adds a pattern for the messages of the given system, e.g. game or chat.
+// An empty system matches the messages of all systems.
+// The expression is matched against the message without the system prefix.
+// Patterns that are registered later take precedence, which allows mods to
+// override the default patterns.
+func (p *Parser) Register(system, expr string, parse ParseFunc) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.patterns = append(p.patterns, pattern{
+		system: system,
+		re:     re,
+		parse:  parse,
+	})
+	return nil
+}
+
+// MustRegister is like Register but panics if the expression cannot be compiled
+func (p *Parser) MustRegister(system, expr string, parse ParseFunc) {
+	err := p.Register(system, expr, parse)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Parse returns the event of the line.
+// Lines that no pattern matches are returned as *Unknown.
+func (p *Parser) Parse(raw string) Event {
+	line := ParseLine(raw)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for i := len(p.patterns) - 1; i >= 0; i-- {
+		pat := p.patterns[i]
+		if pat.system != "" && pat.system != line.System {
+			continue
+		}
+		match := pat.re.FindStringSubmatch(line.Message)
+		if match == nil {
+			continue
+		}
+		if e := pat.parse(line, match); e != nil {
+			return e
+		}
+	}
+	return &Unknown{Line: line}
+}
+
+// ParseLine splits a console line into its timestamp, system and message.
+// Lines without a system are returned with the whole line as message.
+func ParseLine(raw string) Line {
+	raw = strings.TrimRight(raw, "\r\n\x00")
+	line := Line{
+		Message: raw,
+		Raw:     raw,
+	}
+
+	if m := ddnetLine.FindStringSubmatch(raw); m != nil {
+		line.Time = parseTime(m[1])
+		line.Level = m[2]
+		line.System = m[3]
+		line.Message = m[4]
+	} else if m := vanillaLine.FindStringSubmatch(raw); m != nil {
+		line.Time = parseTime(m[1])
+		line.System = m[2]
+		line.Message = m[3]
+	}
+	return line
+}
+
+func parseTime(s string) time.Time {
+	t, err := time.ParseInLocation(timeLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// atoi returns 0 for invalid numbers, the patterns only capture digits
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+func player(id, name string) Player {
+	return Player{
+		ID:   atoi(id),
+		Name: name,
+	}
+}
+
+// stripAddress removes the <{ }> that DDNet puts around addresses
+func stripAddress(addr string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "<{"), "}>")
+}
+
+var defaultPatterns = []struct {
+	system string
+	expr   string
+	parse  ParseFunc
+}{
+	{"server", `^player has entered the game\. ClientID=(\d+) addr=(\S+)`, func(l Line, m []string) Event {
+		return &ClientEntered{Line: l, ID: atoi(m[1]), Address: stripAddress(m[2])}
+	}},
+	{"server", `^client dropped\. cid=(\d+) addr=(\S+) reason='(.*)'$`, func(l Line, m []string) Event {
+		return &ClientDropped{Line: l, ID: atoi(m[1]), Address: stripAddress(m[2]), Reason: m[3]}
+	}},
+	{"game", `^team_join player='(-?\d+):(.*)' (?:m_)?[Tt]eam=(-?\d+)$`, func(l Line, m []string) Event {
+		return &TeamJoin{Line: l, Player: player(m[1], m[2]), Team: atoi(m[3])}
+	}},
+	{"game", `^leave player='(-?\d+):(.*)'$`, func(l Line, m []string) Event {
+		return &Leave{Line: l, Player: player(m[1], m[2])}
+	}},
+	{"chat", `^(-?\d+):(-?\d+):(.*?): (.*)$`, func(l Line, m []string) Event {
+		return &Chat{Line: l, Player: player(m[1], m[3]), Team: atoi(m[2]), Text: m[4]}
+	}},
+	{"teamchat", `^(-?\d+):(-?\d+):(.*?): (.*)$`, func(l Line, m []string) Event {
+		return &TeamChat{Line: l, Player: player(m[1], m[3]), Team: atoi(m[2]), Text: m[4]}
+	}},
+	{"chat", `^\*\*\* (.*)$`, func(l Line, m []string) Event {
+		return &ServerChat{Line: l, Text: m[1]}
+	}},
+	{"chat", `^\*\*\* Vote (passed|failed)`, func(l Line, m []string) Event {
+		return &VoteEnd{Line: l, Passed: m[1] == "passed"}
+	}},
+	{"game", `^kill killer='(-?\d+):(.*)' victim='(-?\d+):(.*)' weapon=(-?\d+) special=(-?\d+)$`, func(l Line, m []string) Event {
+		return &Kill{
+			Line:    l,
+			Killer:  player(m[1], m[2]),
+			Victim:  player(m[3], m[4]),
+			Weapon:  Weapon(atoi(m[5])),
+			Special: atoi(m[6]),
+		}
+	}},
+	{"game", `^flag_grab player='(-?\d+):(.*?)'(?: team=(\d+))?$`, func(l Line, m []string) Event {
+		return &FlagGrab{Line: l, Player: player(m[1], m[2]), Team: flagTeam(m[3])}
+	}},
+	{"game", `^flag_capture player='(-?\d+):(.*?)'(?: team=(\d+))?$`, func(l Line, m []string) Event {
+		return &FlagCapture{Line: l, Player: player(m[1], m[2]), Team: flagTeam(m[3])}
+	}},
+	{"server", `^maps/(.+)\.map (?:crc|sha256) is `, func(l Line, m []string) Event {
+		return &MapChange{Line: l, Map: m[1]}
+	}},
+	{"datafile", `^loading done\. datafile='maps/(.+)\.map'$`, func(l Line, m []string) Event {
+		return &MapChange{Line: l, Map: m[1]}
+	}},
+	{"server", `^'(-?\d+):(.*)' voted (\w+) '(.*)' reason='(.*)' cmd='(.*)' force=(\d)$`, func(l Line, m []string) Event {
+		return &VoteStart{
+			Line:        l,
+			Player:      player(m[1], m[2]),
+			Type:        m[3],
+			Description: m[4],
+			Reason:      m[5],
+			Command:     m[6],
+			Forced:      m[7] != "0",
+		}
+	}},
+	{"server", `^ClientID=(\d+) (?:key=(\S+) )?authed(?: with key=(\S+))?(?: \((.+)\))?$`, func(l Line, m []string) Event {
+		key := m[2]
+		if key == "" {
+			key = m[3]
+		}
+		return &RconLogin{Line: l, ID: atoi(m[1]), Key: key, Level: m[4]}
+	}},
+	{"net_ban", `^banned '(.*)' for (\d+) minutes? \((.*)\)$`, func(l Line, m []string) Event {
+		return &Ban{Line: l, Address: m[1], Duration: time.Duration(atoi(m[2])) * time.Minute, Reason: m[3]}
+	}},
+	{"net_ban", `^banned '(.*)' for life \((.*)\)$`, func(l Line, m []string) Event {
+		return &Ban{Line: l, Address: m[1], Reason: m[2]}
+	}},
+	{"net_ban", `^unbanned (?:index \d+ \()?'(.*)'\)?$`, func(l Line, m []string) Event {
+		return &Unban{Line: l, Address: m[1]}
+	}},
+}
+
+func flagTeam(team string) int {
+	if team == "" {
+		return -1
+	}
+	return atoi(team)
+}
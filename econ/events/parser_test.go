@@ -0,0 +1,161 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/econ/events"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func TestParseLine(t *testing.T) {
+	t.Parallel()
+
+	line := events.ParseLine("[server]: player has entered the game\x00\x00")
+	require.Equal(t, "server", line.System)
+	require.Equal(t, "player has entered the game", line.Message)
+	require.True(t, line.Time.IsZero())
+
+	line = events.ParseLine("[2024-03-01 18:30:00][chat]: 0:-2:nameless tee: hi")
+	require.Equal(t, "chat", line.System)
+	require.Equal(t, "0:-2:nameless tee: hi", line.Message)
+	require.Equal(t, time.Date(2024, 3, 1, 18, 30, 0, 0, time.Local), line.Time)
+
+	line = events.ParseLine("2024-03-01 18:30:00 I chat: 0:-2:nameless tee: hi")
+	require.Equal(t, "I", line.Level)
+	require.Equal(t, "chat", line.System)
+	require.Equal(t, "0:-2:nameless tee: hi", line.Message)
+
+	line = events.ParseLine("Enter password:")
+	require.Equal(t, "", line.System)
+	require.Equal(t, "Enter password:", line.Message)
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	p := events.NewParser()
+	nameless := events.Player{ID: 0, Name: "nameless tee"}
+	brainless := events.Player{ID: 1, Name: "brain'less: tee"}
+
+	for _, test := range []struct {
+		line     string
+		expected events.Event
+	}{
+		{"[server]: player has entered the game. ClientID=0 addr=127.0.0.1:55555", &events.ClientEntered{ID: 0, Address: "127.0.0.1:55555"}},
+		{"2024-03-01 18:30:00 I server: player has entered the game. ClientID=3 addr=<{127.0.0.1:55555}> sixup=0", &events.ClientEntered{ID: 3, Address: "127.0.0.1:55555"}},
+		{"[server]: client dropped. cid=1 addr=127.0.0.1:55555 reason='Timeout'", &events.ClientDropped{ID: 1, Address: "127.0.0.1:55555", Reason: "Timeout"}},
+		{"[game]: team_join player='0:nameless tee' team=1", &events.TeamJoin{Player: nameless, Team: 1}},
+		{"[game]: team_join player='0:nameless tee' m_Team=-1", &events.TeamJoin{Player: nameless, Team: -1}},
+		{"[game]: leave player='1:brain'less: tee'", &events.Leave{Player: brainless}},
+		{"[chat]: 0:-2:nameless tee: hello: world", &events.Chat{Player: nameless, Team: -2, Text: "hello: world"}},
+		{"[teamchat]: 0:1:nameless tee: go", &events.TeamChat{Player: nameless, Team: 1, Text: "go"}},
+		{"[chat]: *** Welcome", &events.ServerChat{Text: "Welcome"}},
+		{"[chat]: *** Vote passed", &events.VoteEnd{Passed: true}},
+		{"[chat]: *** Vote failed", &events.VoteEnd{Passed: false}},
+		{"[game]: kill killer='0:nameless tee' victim='1:brain'less: tee' weapon=3 special=1", &events.Kill{Killer: nameless, Victim: brainless, Weapon: events.WeaponGrenade, Special: 1}},
+		{"[game]: kill killer='0:nameless tee' victim='0:nameless tee' weapon=-2 special=0", &events.Kill{Killer: nameless, Victim: nameless, Weapon: events.WeaponSelf}},
+		{"[game]: flag_grab player='0:nameless tee'", &events.FlagGrab{Player: nameless, Team: -1}},
+		{"[game]: flag_capture player='0:nameless tee' team=1", &events.FlagCapture{Player: nameless, Team: 1}},
+		{"[server]: maps/ctf5.map crc is 1234abcd", &events.MapChange{Map: "ctf5"}},
+		{"[datafile]: loading done. datafile='maps/dm1.map'", &events.MapChange{Map: "dm1"}},
+		{"[server]: '0:nameless tee' voted kick '1:brain'less: tee' reason='spam' cmd='ban 1 5 spam' force=0", &events.VoteStart{Player: nameless, Type: "kick", Description: "1:brain'less: tee", Reason: "spam", Command: "ban 1 5 spam"}},
+		{"[server]: ClientID=0 authed (admin)", &events.RconLogin{ID: 0, Level: "admin"}},
+		{"2024-03-01 18:30:00 I server: ClientID=2 key=default_mod authed (moderator)", &events.RconLogin{ID: 2, Key: "default_mod", Level: "moderator"}},
+		{"[net_ban]: banned '127.0.0.1' for 5 minutes (spam)", &events.Ban{Address: "127.0.0.1", Duration: 5 * time.Minute, Reason: "spam"}},
+		{"[net_ban]: banned '127.0.0.1' for life (cheating)", &events.Ban{Address: "127.0.0.1", Reason: "cheating"}},
+		{"[net_ban]: unbanned index 0 ('127.0.0.1')", &events.Unban{Address: "127.0.0.1"}},
+		{"[server]: something else", &events.Unknown{}},
+	} {
+		e := p.Parse(test.line)
+		line := e.RawLine()
+		require.Equal(t, test.line, line.Raw)
+		setLine(test.expected, line)
+		require.Equal(t, test.expected, e, test.line)
+	}
+}
+
+// setLine sets the embedded line of the expected event in order to compare it with the parsed one
+func setLine(e events.Event, line events.Line) {
+	switch e := e.(type) {
+	case *events.Unknown:
+		e.Line = line
+	case *events.ClientEntered:
+		e.Line = line
+	case *events.ClientDropped:
+		e.Line = line
+	case *events.TeamJoin:
+		e.Line = line
+	case *events.Leave:
+		e.Line = line
+	case *events.Chat:
+		e.Line = line
+	case *events.TeamChat:
+		e.Line = line
+	case *events.ServerChat:
+		e.Line = line
+	case *events.VoteEnd:
+		e.Line = line
+	case *events.Kill:
+		e.Line = line
+	case *events.FlagGrab:
+		e.Line = line
+	case *events.FlagCapture:
+		e.Line = line
+	case *events.MapChange:
+		e.Line = line
+	case *events.VoteStart:
+		e.Line = line
+	case *events.RconLogin:
+		e.Line = line
+	case *events.Ban:
+		e.Line = line
+	case *events.Unban:
+		e.Line = line
+	}
+}
+
+// race is a custom event of a race mod
+type race struct {
+	events.Line
+	Player events.Player
+	Time   string
+}
+
+func TestParserRegister(t *testing.T) {
+	t.Parallel()
+	p := events.NewParser()
+
+	err := p.Register("game", `(`, nil)
+	require.NotNil(t, err)
+
+	p.MustRegister("game", `^finish player='(\d+):(.*)' time=(\S+)$`, func(line events.Line, m []string) events.Event {
+		return &race{Line: line, Player: events.Player{ID: 1, Name: m[2]}, Time: m[3]}
+	})
+	e, ok := p.Parse("[game]: finish player='1:runner' time=12.34").(*race)
+	require.True(t, ok)
+	require.Equal(t, "runner", e.Player.Name)
+	require.Equal(t, "12.34", e.Time)
+
+	// later patterns take precedence, returning nil falls back to the previous ones
+	p.MustRegister("chat", `^(-?\d+):(-?\d+):(.*?): (.*)$`, func(line events.Line, m []string) events.Event {
+		if m[4] != "/timeout" {
+			return nil
+		}
+		return &race{Line: line, Player: events.Player{Name: m[3]}}
+	})
+	_, ok = p.Parse("[chat]: 0:-2:runner: /timeout").(*race)
+	require.True(t, ok)
+	_, ok = p.Parse("[chat]: 0:-2:runner: hello").(*events.Chat)
+	require.True(t, ok)
+
+	var empty events.Parser
+	_, ok = empty.Parse("[chat]: 0:-2:runner: hello").(*events.Unknown)
+	require.True(t, ok)
+}
+
+func TestWeaponString(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "grenade", events.WeaponGrenade.String())
+	require.Equal(t, "world", events.WeaponWorld.String())
+	require.Equal(t, "42", events.Weapon(42).String())
+}
@@ -0,0 +1,147 @@
+package econ
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jxsl13/twapi/econ/events"
+)
+
+// Exec executes the command in the external console and returns its output lines.
+//
+// The command is framed by two echo commands with random markers, so that players cannot end
+// the output early by writing a marker into the chat. The reader passes all
+// lines outside of the markers to the subscribers and the lines between them to Exec.
+// Log lines that the server prints while it executes the command, e.g. chat messages,
+// end up in the output, because the econ does not tell them apart.
+//
+// The command is not sent again in case the connection is lost, as most admin commands
+// must not be executed twice. Exec returns ErrConnectionLost instead.
+// If the context is done before the output was read, the remaining output is discarded.
+func (c *Conn) Exec(ctx context.Context, cmd string) ([]string, error) {
+	if strings.ContainsAny(cmd, "\r\n") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCommand, cmd)
+	}
+
+	w, err := c.startExec(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-w.done:
+		if w.err != nil {
+			return nil, w.err
+		}
+		return w.lines, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		w.abandoned = true
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// startExec registers the markers of the command at the reader and writes the command
+func (c *Conn) startExec(ctx context.Context, cmd string) (*execWaiter, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	w := &execWaiter{
+		begin: "twapi-exec-" + nonce + "-begin",
+		end:   "twapi-exec-" + nonce + "-end",
+		lines: []string{},
+		done:  make(chan struct{}),
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	for {
+		tc, generation, err := c.conn(ctx, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		if c.telnetConn != tc || c.generation != generation {
+			// reconnected in the meantime
+			c.mu.Unlock()
+			continue
+		}
+		c.waiters = append(c.waiters, w)
+		c.mu.Unlock()
+
+		err = writeLines(tc, "echo "+w.begin, cmd, "echo "+w.end)
+		if err != nil {
+			// the reader fails the waiter when it reconnects
+			c.invalidate(tc)
+			return nil, err
+		}
+		return w, nil
+	}
+}
+
+// dispatch passes the line either to the Exec call that waits for it or to ReadLine and the subscribers
+func (c *Conn) dispatch(line string) {
+	c.mu.Lock()
+	if len(c.waiters) > 0 {
+		// the commands are executed in the order in which they were written
+		w := c.waiters[0]
+		switch {
+		case !w.started && isMarker(line, w.begin):
+			w.started = true
+			c.mu.Unlock()
+			return
+		case w.started && isMarker(line, w.end):
+			c.waiters = c.waiters[1:]
+			c.mu.Unlock()
+			w.finish(nil)
+			return
+		case w.started:
+			if !w.abandoned {
+				w.lines = append(w.lines, line)
+			}
+			c.mu.Unlock()
+			return
+		}
+	}
+	c.mu.Unlock()
+	c.publish(line)
+}
+
+// execWaiter collects the output of a command
+type execWaiter struct {
+	begin     string
+	end       string
+	started   bool
+	abandoned bool
+	lines     []string
+	err       error
+	done      chan struct{}
+}
+
+func (w *execWaiter) finish(err error) {
+	w.err = err
+	close(w.done)
+}
+
+// isMarker returns true if the line is the output of echo marker, e.g. [Console]: marker
+func isMarker(line, marker string) bool {
+	l := events.ParseLine(line)
+	return strings.EqualFold(l.System, "console") && l.Message == marker
+}
+
+// newNonce returns a random hex string that cannot be guessed by players
+func newNonce() (string, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,157 @@
+package econ_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/econ"
+	"github.com/jxsl13/twapi/econ/econtest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+func newServer(t *testing.T) *econtest.Server {
+	t.Helper()
+	s, err := econtest.NewServer("secret")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+	return s
+}
+
+func dial(t *testing.T, s *econtest.Server, options ...econ.Option) *econ.Conn {
+	t.Helper()
+	options = append([]econ.Option{econ.WithMaxReconnectDelay(100 * time.Millisecond)}, options...)
+	conn, err := econ.DialTo(s.Addr, "secret", options...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("status", func(args string) []string {
+		// a log line that is printed before the command is executed
+		s.Send("[chat]: 0:-2:nameless tee: hi")
+		return []string{
+			"[Server]: id=0 addr=127.0.0.1:55555 name='nameless tee' score=0",
+			"[Server]: id=1 addr=127.0.0.1:55556 name='brainless tee' score=3",
+		}
+	})
+	conn := dial(t, s)
+
+	ctx := context.Background()
+	lines, err := conn.Exec(ctx, "status")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"[chat]: 0:-2:nameless tee: hi",
+		"[Server]: id=0 addr=127.0.0.1:55555 name='nameless tee' score=0",
+		"[Server]: id=1 addr=127.0.0.1:55556 name='brainless tee' score=3",
+	}, lines)
+
+	lines, err = conn.Exec(ctx, "say hello")
+	require.NoError(t, err)
+	require.Equal(t, []string{"[Console]: No such command: say."}, lines)
+
+	_, err = conn.Exec(ctx, "say a\nshutdown")
+	require.ErrorIs(t, econ.ErrInvalidCommand, err)
+}
+
+func TestExecPassesOtherLines(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("bans", func(args string) []string {
+		return []string{"[net_ban]: 0 ban(s)"}
+	})
+	conn := dial(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := conn.Subscribe(ctx)
+
+	// wait until the connection is registered before sending log lines
+	for s.Connections() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s.Send("[server]: first")
+	s.Send("[server]: second")
+
+	output, err := conn.Exec(context.Background(), "bans")
+	require.NoError(t, err)
+	require.Equal(t, []string{"[net_ban]: 0 ban(s)"}, output)
+
+	for _, expected := range []string{"[server]: first", "[server]: second"} {
+		require.Equal(t, expected, <-lines)
+	}
+}
+
+func TestExecContext(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	block := make(chan struct{})
+	s.Handle("wait", func(args string) []string {
+		<-block
+		return []string{"[Console]: late"}
+	})
+	s.Handle("ping", func(args string) []string {
+		return []string{"[Console]: pong"}
+	})
+	conn := dial(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := conn.Exec(ctx, "wait")
+	require.ErrorIs(t, context.DeadlineExceeded, err)
+	close(block)
+
+	// the output of the abandoned command is discarded
+	lines, err := conn.Exec(context.Background(), "ping")
+	require.NoError(t, err)
+	require.Equal(t, []string{"[Console]: pong"}, lines)
+}
+
+func TestExecSpoofedMarker(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("echo", func(args string) []string {
+		if strings.HasSuffix(args, "-end") {
+			// a player that writes the end marker into the chat
+			s.Send("[chat]: 0:-2:evil: " + args)
+			s.Send("[chat]: 0:-2:evil: [Console]: " + args)
+		}
+		return []string{"[Console]: " + args}
+	})
+	s.Handle("ping", func(args string) []string {
+		return []string{"[Console]: pong"}
+	})
+	conn := dial(t, s)
+
+	lines, err := conn.Exec(context.Background(), "ping")
+	require.NoError(t, err)
+	require.Len(t, 3, lines)
+	require.Equal(t, "[Console]: pong", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "[chat]: 0:-2:evil: twapi-exec-"))
+	require.True(t, strings.HasPrefix(lines[2], "[chat]: 0:-2:evil: [Console]: twapi-exec-"))
+
+	// markers are not reused
+	s.Handle("echo", func(args string) []string {
+		return []string{"[Console]: " + args}
+	})
+	_, err = conn.Exec(context.Background(), "ping")
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), "ping")
+	require.NoError(t, err)
+	var echoes []string
+	for _, cmd := range s.Commands() {
+		if strings.HasPrefix(cmd, "echo ") {
+			echoes = append(echoes, cmd)
+		}
+	}
+	require.Len(t, 6, echoes)
+	require.False(t, echoes[0] == echoes[2] || echoes[2] == echoes[4])
+}
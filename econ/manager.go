@@ -0,0 +1,369 @@
+package econ
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// NewManager creates a manager that connects to many servers at once.
+// The options are applied to every connection before the options of Add.
+func NewManager(options ...Option) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ctx:     ctx,
+		cancel:  cancel,
+		options: options,
+		servers: make(map[string]*server),
+		subs:    make(map[*managerSubscription]struct{}),
+	}
+}
+
+// Manager keeps named econ connections to many servers, fans out commands to all of them
+// and merges their lines into a single stream.
+// It is safe for concurrent use.
+type Manager struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	options []Option
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	servers map[string]*server
+	subs    map[*managerSubscription]struct{}
+	closed  bool
+}
+
+// server is a connection of the manager that may still be connecting
+type server struct {
+	name    string
+	address string
+	cancel  context.CancelFunc
+	// dialed is closed as soon as DialTo returned
+	dialed chan struct{}
+	conn   *Conn
+	err    error
+}
+
+// ServerLine is a line of a server of the manager
+type ServerLine struct {
+	Server string
+	Line   string
+}
+
+// Result is the output of a command of a single server
+type Result struct {
+	Server string
+	Lines  []string
+	Err    error
+}
+
+// Health is the connection state of a single server
+type Health struct {
+	Server  string
+	Address string
+	// Connected is false while connecting or reconnecting
+	Connected  bool
+	Reconnects int
+	// Err is the reason why the server is not connected, e.g. a failed authentication
+	Err error
+}
+
+// Add connects to the server in the background and registers it with the name.
+// The connection attempts are retried with a backoff until they succeed or the authentication fails.
+func (m *Manager) Add(name, address, password string, options ...Option) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	if _, ok := m.servers[name]; ok {
+		return fmt.Errorf("%w: %s", ErrServerExists, name)
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	s := &server{
+		name:    name,
+		address: address,
+		cancel:  cancel,
+		dialed:  make(chan struct{}),
+	}
+	m.servers[name] = s
+
+	opts := make([]Option, 0, len(m.options)+len(options)+2)
+	opts = append(opts, m.options...)
+	opts = append(opts, options...)
+	opts = append(opts, WithContext(ctx), WithReadLineBuffer(0))
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		conn, err := DialTo(address, password, opts...)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		defer close(s.dialed)
+		s.conn, s.err = conn, err
+		if err != nil {
+			return
+		}
+		for sub := range m.subs {
+			sub.forward(s.name, conn)
+		}
+	}()
+	return nil
+}
+
+// Remove closes the connection to the server and removes it from the manager
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	s, ok := m.servers[name]
+	delete(m.servers, name)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownServer, name)
+	}
+	return s.close()
+}
+
+// Conn returns the connection of the server, ErrNotConnected before the first connection was established.
+// The lines of the connection can only be read with Subscribe, ReadLine returns ErrReadLineDisabled.
+func (m *Manager) Conn(name string) (*Conn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.servers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownServer, name)
+	}
+	return s.connection()
+}
+
+// Names returns the sorted names of all servers
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Health returns the connection state of all servers sorted by name
+func (m *Manager) Health() []Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]Health, 0, len(m.servers))
+	for _, s := range m.servers {
+		h := Health{
+			Server:  s.name,
+			Address: s.address,
+			Err:     s.err,
+		}
+		if s.conn != nil {
+			h.Connected = s.conn.Connected()
+			h.Reconnects = s.conn.Reconnects()
+			h.Err = s.conn.Err()
+		}
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Server < result[j].Server
+	})
+	return result
+}
+
+// Each calls f concurrently for the connection of every server and joins the errors.
+// Servers that are not connected yet are reported with ErrNotConnected.
+func (m *Manager) Each(ctx context.Context, f func(ctx context.Context, name string, c *Conn) error) error {
+	results := m.each(ctx, func(ctx context.Context, name string, c *Conn) ([]string, error) {
+		return nil, f(ctx, name, c)
+	})
+	errs := make([]error, 0, len(results))
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Server, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Exec executes the command on all servers and returns their output sorted by server name
+func (m *Manager) Exec(ctx context.Context, cmd string) []Result {
+	return m.each(ctx, func(ctx context.Context, name string, c *Conn) ([]string, error) {
+		return c.Exec(ctx, cmd)
+	})
+}
+
+// Say sends the chat message on all servers
+func (m *Manager) Say(ctx context.Context, message string) error {
+	return m.Each(ctx, func(ctx context.Context, name string, c *Conn) error {
+		return c.Say(ctx, message)
+	})
+}
+
+// Broadcast shows the message in the center of the screen of all players on all servers
+func (m *Manager) Broadcast(ctx context.Context, message string) error {
+	return m.Each(ctx, func(ctx context.Context, name string, c *Conn) error {
+		return c.Broadcast(ctx, message)
+	})
+}
+
+// Ban bans the ip on all servers, zero minutes ban permanently
+func (m *Manager) Ban(ctx context.Context, ip string, minutes int, reason string) error {
+	return m.Each(ctx, func(ctx context.Context, name string, c *Conn) error {
+		return c.Ban(ctx, ip, minutes, reason)
+	})
+}
+
+// Unban removes the ban of the ip on all servers
+func (m *Manager) Unban(ctx context.Context, ip string) error {
+	return m.Each(ctx, func(ctx context.Context, name string, c *Conn) error {
+		return c.Unban(ctx, ip)
+	})
+}
+
+func (m *Manager) each(ctx context.Context, f func(ctx context.Context, name string, c *Conn) ([]string, error)) []Result {
+	m.mu.Lock()
+	servers := make([]*server, 0, len(m.servers))
+	for _, s := range m.servers {
+		servers = append(servers, s)
+	}
+	m.mu.Unlock()
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].name < servers[j].name
+	})
+
+	var wg sync.WaitGroup
+	results := make([]Result, len(servers))
+	for i, s := range servers {
+		wg.Add(1)
+		go func(r *Result, s *server) {
+			defer wg.Done()
+			r.Server = s.name
+
+			m.mu.Lock()
+			c, err := s.connection()
+			m.mu.Unlock()
+			if err != nil {
+				r.Err = err
+				return
+			}
+			r.Lines, r.Err = f(ctx, s.name, c)
+		}(&results[i], s)
+	}
+	wg.Wait()
+	return results
+}
+
+// Subscribe returns a channel that receives the lines of all servers tagged with the server name.
+// Servers that connect later are included as well. The channel is closed when the context
+// is done or the manager is closed.
+// Like Conn.Subscribe, no line is lost while the subscriber lags behind.
+func (m *Manager) Subscribe(ctx context.Context) <-chan ServerLine {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &managerSubscription{
+		ctx:    ctx,
+		cancel: cancel,
+		out:    make(chan ServerLine, subscriptionBuffer),
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		cancel()
+		close(sub.out)
+		return sub.out
+	}
+	m.subs[sub] = struct{}{}
+	for _, s := range m.servers {
+		if s.conn != nil {
+			sub.forward(s.name, s.conn)
+		}
+	}
+	m.mu.Unlock()
+
+	context.AfterFunc(ctx, func() {
+		m.mu.Lock()
+		delete(m.subs, sub)
+		m.mu.Unlock()
+
+		sub.wg.Wait()
+		close(sub.out)
+	})
+	return sub.out
+}
+
+// Close closes all connections and subscriptions
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	servers := m.servers
+	m.servers = make(map[string]*server)
+	for sub := range m.subs {
+		sub.cancel()
+	}
+	m.mu.Unlock()
+	m.cancel()
+
+	errs := make([]error, 0, len(servers))
+	for name, s := range servers {
+		if err := s.close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	m.wg.Wait()
+	return errors.Join(errs...)
+}
+
+// connection must be called with the manager's lock
+func (s *server) connection() (*Conn, error) {
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return nil, ErrNotConnected
+}
+
+// close aborts the connection attempts and closes the connection
+func (s *server) close() error {
+	s.cancel()
+	<-s.dialed
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// managerSubscription merges the subscriptions of all connections
+type managerSubscription struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	out    chan ServerLine
+	wg     sync.WaitGroup
+}
+
+// forward must be called with the manager's lock in order not to race with the closing of out
+func (sub *managerSubscription) forward(name string, c *Conn) {
+	if sub.ctx.Err() != nil {
+		return
+	}
+	lines := c.Subscribe(sub.ctx)
+	sub.wg.Add(1)
+	go func() {
+		defer sub.wg.Done()
+		for line := range lines {
+			select {
+			case sub.out <- ServerLine{Server: name, Line: line}:
+			case <-sub.ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,130 @@
+package econ_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/econ"
+	"github.com/jxsl13/twapi/econ/econtest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// waitConnected waits until all servers of the manager are connected
+func waitConnected(t *testing.T, m *econ.Manager) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		connected := true
+		for _, h := range m.Health() {
+			connected = connected && h.Connected
+		}
+		if connected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("servers are not connected: %v", m.Health())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+	a, b := newServer(t), newServer(t)
+	for _, s := range []*econtest.Server{a, b} {
+		s.Handle("ban", func(args string) []string { return nil })
+		s.Handle("ping", func(args string) []string { return []string{"[Console]: pong"} })
+	}
+
+	m := econ.NewManager(econ.WithMaxReconnectDelay(100 * time.Millisecond))
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := m.Subscribe(ctx)
+
+	require.NoError(t, m.Add("b", b.Addr, "secret"))
+	require.NoError(t, m.Add("a", a.Addr, "secret"))
+	require.ErrorIs(t, econ.ErrServerExists, m.Add("a", a.Addr, "secret"))
+	require.Equal(t, []string{"a", "b"}, m.Names())
+	waitConnected(t, m)
+	waitConnections(t, a, 1)
+	waitConnections(t, b, 1)
+
+	// the stream includes the servers that connected after the subscription
+	a.Send("[server]: from a")
+	require.Equal(t, econ.ServerLine{Server: "a", Line: "[server]: from a"}, <-lines)
+	b.Send("[server]: from b")
+	require.Equal(t, econ.ServerLine{Server: "b", Line: "[server]: from b"}, <-lines)
+
+	results := m.Exec(context.Background(), "ping")
+	require.Equal(t, []econ.Result{
+		{Server: "a", Lines: []string{"[Console]: pong"}},
+		{Server: "b", Lines: []string{"[Console]: pong"}},
+	}, results)
+
+	require.NoError(t, m.Ban(context.Background(), "10.0.0.1", 5, "spam"))
+	require.Equal(t, "ban \"10.0.0.1\" 5 \"spam\"", a.Commands()[len(a.Commands())-2])
+	require.Equal(t, "ban \"10.0.0.1\" 5 \"spam\"", b.Commands()[len(b.Commands())-2])
+
+	// say is not known by the fake servers
+	err := m.Say(context.Background(), "hi")
+	require.ErrorIs(t, econ.ErrCommandFailed, err)
+
+	conn, err := m.Conn("a")
+	require.NoError(t, err)
+	require.True(t, conn.Connected())
+	_, err = conn.ReadLine()
+	require.ErrorIs(t, econ.ErrReadLineDisabled, err)
+	_, err = m.Conn("c")
+	require.ErrorIs(t, econ.ErrUnknownServer, err)
+
+	require.NoError(t, m.Remove("b"))
+	require.Equal(t, []string{"a"}, m.Names())
+	waitConnections(t, b, 0)
+
+	require.NoError(t, m.Close())
+	_, ok := <-lines
+	require.False(t, ok)
+	require.ErrorIs(t, econ.ErrClosed, m.Add("b", b.Addr, "secret"))
+}
+
+func TestManagerHealth(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+
+	m := econ.NewManager(econ.WithMaxReconnectDelay(100 * time.Millisecond))
+	defer m.Close()
+	require.NoError(t, m.Add("valid", s.Addr, "secret"))
+	require.NoError(t, m.Add("invalid", s.Addr, "wrong"))
+
+	// the authentication failure is not retried
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		health := m.Health()
+		require.Len(t, 2, health)
+		if health[0].Err != nil && health[1].Connected {
+			require.Equal(t, "invalid", health[0].Server)
+			require.ErrorIs(t, econ.ErrAuthenticationFailed, health[0].Err)
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected health: %v", health)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	err := m.Each(context.Background(), func(ctx context.Context, name string, c *econ.Conn) error {
+		return nil
+	})
+	require.ErrorIs(t, econ.ErrAuthenticationFailed, err)
+
+	waitConnections(t, s, 1)
+	s.Disconnect()
+	for m.Health()[1].Reconnects == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, "valid", m.Health()[1].Server)
+	require.True(t, m.Health()[1].Connected)
+}
@@ -27,3 +27,11 @@ func WithOnConnectCommands(commands ...string) Option {
 		c.authCommandList = commands
 	}
 }
+
+// WithReadLineBuffer sets the number of lines that are buffered for ReadLine, the default is 1024.
+// Zero disables ReadLine, which is useful for connections whose lines are only read with Subscribe.
+func WithReadLineBuffer(lines int) Option {
+	return func(c *Conn) {
+		c.readLineBuffer = lines
+	}
+}
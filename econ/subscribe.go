@@ -0,0 +1,147 @@
+package econ
+
+import (
+	"context"
+	"sync"
+)
+
+// subscription receives the lines of the reader
+type subscription struct {
+	queue *lineQueue
+	ch    chan string
+}
+
+// Subscribe returns a channel that receives every line that is read after the call,
+// except for the output of Exec. The channel is closed when the context is done or
+// the connection is closed and all remaining lines were received.
+//
+// No line is lost or duplicated. The reader never waits for a subscriber, the lines
+// of a subscriber that lags behind are queued until it catches up. Subscribers must
+// either receive until the channel is closed or cancel the context.
+func (c *Conn) Subscribe(ctx context.Context) <-chan string {
+	s := &subscription{
+		queue: newLineQueue(0),
+		ch:    make(chan string, subscriptionBuffer),
+	}
+
+	c.subMu.Lock()
+	if c.subsClosed {
+		c.subMu.Unlock()
+		close(s.ch)
+		return s.ch
+	}
+	c.subscriptions[s] = struct{}{}
+	c.subMu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() {
+		c.subMu.Lock()
+		delete(c.subscriptions, s)
+		c.subMu.Unlock()
+		s.queue.close()
+	})
+	go func() {
+		defer stop()
+		s.forward(ctx)
+	}()
+	return s.ch
+}
+
+// forward passes the queued lines to the channel until the queue is closed and empty
+func (s *subscription) forward(ctx context.Context) {
+	defer close(s.ch)
+	for {
+		line, ok := s.queue.pop()
+		if !ok {
+			return
+		}
+		select {
+		case s.ch <- line:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publish passes the line to ReadLine and to all subscribers without blocking
+func (c *Conn) publish(line string) {
+	if c.lines != nil {
+		c.lines.push(line)
+	}
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	for s := range c.subscriptions {
+		s.queue.push(line)
+	}
+}
+
+// closeSubscriptions closes the queues of ReadLine and all subscribers after the reader finished
+func (c *Conn) closeSubscriptions() {
+	if c.lines != nil {
+		c.lines.close()
+	}
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	c.subsClosed = true
+	for s := range c.subscriptions {
+		delete(c.subscriptions, s)
+		s.queue.close()
+	}
+}
+
+// newLineQueue creates a queue that keeps at most limit lines, zero for no limit
+func newLineQueue(limit int) *lineQueue {
+	q := &lineQueue{
+		limit: limit,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// lineQueue is a queue of lines that never blocks the reader.
+// If the limit is reached, the oldest line is discarded.
+type lineQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	lines  []string
+	closed bool
+}
+
+func (q *lineQueue) push(line string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	if q.limit > 0 && len(q.lines) >= q.limit {
+		q.lines[0] = ""
+		q.lines = q.lines[1:]
+	}
+	q.lines = append(q.lines, line)
+	q.cond.Signal()
+}
+
+// pop waits for the next line, it returns false if the queue is closed and empty
+func (q *lineQueue) pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.lines) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.lines) == 0 {
+		return "", false
+	}
+	line := q.lines[0]
+	q.lines[0] = ""
+	q.lines = q.lines[1:]
+	return line, true
+}
+
+func (q *lineQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,272 @@
+package econ_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twapi/econ"
+	"github.com/jxsl13/twapi/econ/econtest"
+	"github.com/jxsl13/twapi/internal/testutils/require"
+)
+
+// waitConnections waits until the server has the given number of authenticated connections
+func waitConnections(t *testing.T, s *econtest.Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Connections() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d connections, got %d", n, s.Connections())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrentReadWrite(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("ping", func(args string) []string {
+		return []string{"[Console]: pong " + args}
+	})
+	s.Handle("say", func(args string) []string {
+		return nil
+	})
+	conn := dial(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, b := conn.Subscribe(ctx), conn.Subscribe(ctx)
+	waitConnections(t, s, 1)
+
+	const lines = 200
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < lines; i++ {
+			s.Send(fmt.Sprintf("[chat]: line %d", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			output, err := conn.Exec(context.Background(), fmt.Sprintf("ping %d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			// log lines may end up in the output, the pong must be the last line
+			if len(output) == 0 || output[len(output)-1] != fmt.Sprintf("[Console]: pong %d", i) {
+				t.Errorf("unexpected output of ping %d: %v", i, output)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			if err := conn.WriteLine(fmt.Sprintf("say %d", i)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	// both subscribers receive the lines in order without duplicates,
+	// except for the ones that were passed to the Exec calls
+	received := func(ch <-chan string) []string {
+		result := []string{}
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case line := <-ch:
+				result = append(result, line)
+			case <-timeout:
+				return result
+			}
+			if line := result[len(result)-1]; line == fmt.Sprintf("[chat]: line %d", lines-1) {
+				return result
+			}
+		}
+	}
+	var ra []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ra = received(a)
+	}()
+	rb := received(b)
+	<-done
+	wg.Wait()
+
+	require.Equal(t, ra, rb)
+	last := -1
+	for _, line := range ra {
+		var i int
+		_, err := fmt.Sscanf(line, "[chat]: line %d", &i)
+		require.NoError(t, err, line)
+		require.Greater(t, last, i)
+		last = i
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("ping", func(args string) []string {
+		return []string{"[Console]: pong"}
+	})
+	conn := dial(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// a subscriber that does not read while the lines are sent
+	sub := conn.Subscribe(ctx)
+	waitConnections(t, s, 1)
+
+	const lines = 300
+	for i := 0; i < lines; i++ {
+		s.Send(fmt.Sprintf("[chat]: line %d", i))
+	}
+
+	// the reader is not blocked by the subscriber
+	execCtx, execCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer execCancel()
+	output, err := conn.Exec(execCtx, "ping")
+	require.NoError(t, err)
+	require.Equal(t, []string{"[Console]: pong"}, output)
+
+	// neither the subscriber nor ReadLine miss any line
+	for i := 0; i < lines; i++ {
+		require.Equal(t, fmt.Sprintf("[chat]: line %d", i), <-sub)
+		line, err := conn.ReadLine()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("[chat]: line %d", i), line)
+	}
+}
+
+func TestReadLineBuffer(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("ping", func(args string) []string {
+		return []string{"[Console]: pong"}
+	})
+	conn := dial(t, s, econ.WithReadLineBuffer(10))
+	waitConnections(t, s, 1)
+
+	for i := 0; i < 20; i++ {
+		s.Send(fmt.Sprintf("[chat]: line %d", i))
+	}
+	_, err := conn.Exec(context.Background(), "ping")
+	require.NoError(t, err)
+
+	// the oldest lines are discarded
+	for i := 10; i < 20; i++ {
+		line, err := conn.ReadLine()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("[chat]: line %d", i), line)
+	}
+
+	disabled := dial(t, s, econ.WithReadLineBuffer(0))
+	_, err = disabled.ReadLine()
+	require.ErrorIs(t, econ.ErrReadLineDisabled, err)
+}
+
+func TestReadLineBuffersEarlyLines(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("ping", func(args string) []string {
+		return []string{"[Console]: pong"}
+	})
+	conn := dial(t, s)
+	waitConnections(t, s, 1)
+
+	s.Send("[server]: early")
+	// the line was read before the first call of ReadLine
+	_, err := conn.Exec(context.Background(), "ping")
+	require.NoError(t, err)
+
+	line, err := conn.ReadLine()
+	require.NoError(t, err)
+	require.Equal(t, "[server]: early", line)
+}
+
+func TestReconnect(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	s.Handle("ping", func(args string) []string {
+		return []string{"[Console]: pong"}
+	})
+	conn := dial(t, s)
+	waitConnections(t, s, 1)
+
+	s.Send("[server]: before")
+	line, err := conn.ReadLine()
+	require.NoError(t, err)
+	require.Equal(t, "[server]: before", line)
+
+	s.Disconnect()
+	// the reader reconnects, the writer waits for the new connection
+	waitConnections(t, s, 1)
+	require.Equal(t, 2, s.Logins())
+
+	s.Send("[server]: after")
+	line, err = conn.ReadLine()
+	require.NoError(t, err)
+	require.Equal(t, "[server]: after", line)
+
+	output, err := conn.Exec(context.Background(), "ping")
+	require.NoError(t, err)
+	require.Equal(t, []string{"[Console]: pong"}, output)
+	require.Equal(t, 2, s.Logins())
+}
+
+func TestExecConnectionLost(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	block := make(chan struct{})
+	s.Handle("wait", func(args string) []string {
+		<-block
+		return nil
+	})
+	conn := dial(t, s)
+	waitConnections(t, s, 1)
+
+	go func() {
+		for len(s.Commands()) < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		s.Disconnect()
+		close(block)
+	}()
+	_, err := conn.Exec(context.Background(), "wait")
+	require.ErrorIs(t, econ.ErrConnectionLost, err)
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := dial(t, s, econ.WithContext(ctx))
+	lines := conn.Subscribe(context.Background())
+	waitConnections(t, s, 1)
+
+	cancel()
+	_, ok := <-lines
+	require.False(t, ok)
+	require.ErrorIs(t, context.Canceled, conn.Err())
+
+	_, err := conn.ReadLine()
+	require.ErrorIs(t, context.Canceled, err)
+	require.ErrorIs(t, context.Canceled, conn.WriteLine("say hi"))
+	require.NoError(t, conn.Close())
+	waitConnections(t, s, 0)
+
+	conn = dial(t, s)
+	require.NoError(t, conn.Close())
+	require.ErrorIs(t, econ.ErrClosed, conn.WriteLine("say hi"))
+	_, ok = <-conn.Subscribe(context.Background())
+	require.False(t, ok)
+}